@@ -0,0 +1,184 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// baselineTo is a copy of To before the plans were cached, which resolved
+// the tags and conversions of every field via reflect on every call. It is
+// kept as the reference of BenchmarkToBaseline.
+func baselineTo(sources Sources, obj interface{}) error {
+	if obj == nil {
+		return errors.New("given struct to fill is nil")
+	}
+
+	if len(sources) == 0 {
+		return nil
+	}
+
+	valueOf := reflect.ValueOf(obj)
+	for valueOf.Kind() == reflect.Ptr {
+		valueOf = valueOf.Elem()
+	}
+
+	t := valueOf.Type()
+	for i := 0; i < valueOf.NumField(); i++ {
+		for _, source := range sources {
+			field := t.Field(i)
+
+			tagValue, ok := field.Tag.Lookup(source.Tag)
+			if !ok {
+				continue
+			}
+
+			property := valueOf.Field(i)
+			if !property.IsValid() || !property.CanSet() {
+				continue
+			}
+
+			var values []string
+			v, err := source.Get(tagValue)
+
+			if v != nil {
+				values = v.values()
+			}
+
+			if err != nil {
+				return newError(ErrSource, tagValue, source.Tag, values, err)
+			}
+
+			if len(values) == 0 {
+				continue
+			}
+
+			err = baselineSetValue(property, values...)
+			if err != nil {
+				return newError(ErrConversion, tagValue, source.Tag, values, err)
+			}
+		}
+	}
+	return nil
+}
+
+func baselineSetValue(property reflect.Value, values ...string) error {
+	switch kind := property.Kind(); kind {
+	case reflect.Ptr:
+		property.Set(reflect.New(property.Type().Elem()))
+		return baselineSetValue(property.Elem(), values...)
+	case reflect.Slice:
+		return baselineSetSlice(property, values)
+	case reflect.String:
+		return setString(property, values)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return baselineSetInt(property, values)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		ui, err := strconv.ParseUint(values[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		property.SetUint(ui)
+		return nil
+	case reflect.Bool:
+		return setBool(property, values)
+	case reflect.Float32:
+		return setFloat32(property, values)
+	case reflect.Float64:
+		return setFloat64(property, values)
+	case reflect.Struct:
+		return baselineSetStruct(property, values)
+	default:
+		return fmt.Errorf("unsupported property kind %q", kind)
+	}
+}
+
+func baselineSetStruct(property reflect.Value, values []string) error {
+	switch property.Interface().(type) {
+	case time.Time:
+		t, err := time.Parse(time.RFC3339, values[0])
+		if err != nil {
+			return err
+		}
+		property.Set(reflect.ValueOf(t))
+	default:
+		s := reflect.New(property.Type())
+		err := json.Unmarshal([]byte(values[0]), s.Interface())
+		if err != nil {
+			return err
+		}
+		property.Set(s.Elem())
+	}
+	return nil
+}
+
+func baselineSetSlice(property reflect.Value, values []string) error {
+	var (
+		propertyType        = property.Type()
+		propertyElementKind = propertyType.Elem().Kind()
+	)
+
+	switch propertyElementKind {
+	// case of a byte array
+	case reflect.Uint8:
+		values = strings.Split(values[0], "")
+		for i, c := range values {
+			values[i] = strconv.FormatUint(uint64([]byte(c)[0]), 10)
+		}
+	}
+
+	var (
+		lenVals = len(values)
+		slice   = reflect.MakeSlice(propertyType, lenVals, lenVals)
+	)
+
+	for i := 0; i < lenVals; i++ {
+		if err := baselineSetValue(slice.Index(i), values[i]); err != nil {
+			return err
+		}
+	}
+
+	property.Set(slice)
+	return nil
+}
+
+func baselineSetInt(property reflect.Value, values []string) error {
+	switch property.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(values[0])
+		if err != nil {
+			return err
+		}
+		property.SetInt(int64(d))
+	default:
+		v, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil {
+			return err
+		}
+		property.SetInt(v)
+	}
+	return nil
+}
@@ -0,0 +1,520 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Option configures a Decoder.
+type Option func(*Decoder)
+
+// CollectErrors makes the decoder continue after a field failed. All failures
+// are returned together as Errors.
+func CollectErrors() Option {
+	return func(d *Decoder) {
+		d.collectErrors = true
+	}
+}
+
+// SplitSeparator splits the values of slice and map fields without a split tag
+// at sep, e.g. to fill ids=1,2&ids=3 as []int{1, 2, 3}. See the split tag.
+func SplitSeparator(sep string) Option {
+	return func(d *Decoder) {
+		d.split = sep
+	}
+}
+
+// StrictArrays makes array fields fail if they receive fewer values than their
+// length. By default the remaining elements are zero.
+func StrictArrays() Option {
+	return func(d *Decoder) {
+		d.strictArrays = true
+	}
+}
+
+// InferTypes fills empty interface fields with bool, int64 or float64 values
+// if the value is valid for one of them, instead of always using strings.
+func InferTypes() Option {
+	return func(d *Decoder) {
+		d.inferTypes = true
+	}
+}
+
+// Prefetch makes the decoder get the values of all fields from the sources
+// before it sets any of them, with up to workers concurrent calls per Decode.
+// This speeds up slow sources, e.g. backed by a remote service, whose Get has
+// to be safe for concurrent use. The fields are still set in the order of the
+// sources, so the result is the same as without prefetching. Values below 1
+// are treated as 1.
+func Prefetch(workers int) Option {
+	return func(d *Decoder) {
+		if workers < 1 {
+			workers = 1
+		}
+		d.workers = workers
+	}
+}
+
+// TimeLayout sets the layout of time.Time fields without a layout tag. It is
+// either a layout of the time package or one of the names rfc3339,
+// rfc3339nano, rfc1123, rfc1123z, rfc822, rfc822z, rfc850, ansic, kitchen,
+// date (2006-01-02), datetime (2006-01-02 15:04:05), time (15:04:05) as well
+// as unix, unixmilli, unixmicro and unixnano for the number of seconds,
+// milliseconds, microseconds or nanoseconds since epoch. The default is
+// RFC3339.
+func TimeLayout(layout string) Option {
+	return func(d *Decoder) {
+		d.timeLayout = layout
+	}
+}
+
+// TimeLocation sets the location of time values which don't contain a time
+// zone. The default is UTC, which is used for a nil location as well.
+func TimeLocation(location *time.Location) Option {
+	return func(d *Decoder) {
+		if location == nil {
+			location = time.UTC
+		}
+		d.timeLocation = location
+	}
+}
+
+// Converter registers the conversion of values to the type T. It takes
+// precedence over the built-in conversions and the Unmarshaler and
+// encoding.TextUnmarshaler interfaces. This way types can be supported which
+// can't implement these interfaces, e.g. those of third-party packages:
+//
+//	handgover.NewDecoder(
+//		handgover.Converter(func(values []string) (*big.Int, error) {
+//			...
+//		}),
+//	)
+//
+// The converter is used for fields of type T as well as e.g. *T, []T and maps
+// with keys or values of type T.
+func Converter[T any](convert func(values []string) (T, error)) Option {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return func(d *Decoder) {
+		if d.converters == nil {
+			d.converters = map[reflect.Type]setter{}
+		}
+		d.converters[t] = func(property reflect.Value, values []string) error {
+			v, err := convert(values)
+			if err != nil {
+				return err
+			}
+			property.Set(reflect.ValueOf(&v).Elem())
+			return nil
+		}
+	}
+}
+
+// Decoder fills structs from sources. In contrast to Sources.To it can be
+// configured with options.
+//
+// A Decoder caches the plans of the struct types it filled, so it should be
+// created once and reused. It is safe for concurrent use.
+type Decoder struct {
+	collectErrors bool
+	converters    map[reflect.Type]setter
+	split         string
+	strictArrays  bool
+	inferTypes    bool
+	timeLayout    string
+	timeLocation  *time.Location
+	workers       int
+
+	// plans caches the plan for every struct type passed to Decode.
+	plans sync.Map
+}
+
+// defaultDecoder is used by Sources.To.
+var defaultDecoder = NewDecoder()
+
+// NewDecoder creates a Decoder with the given options.
+func NewDecoder(opts ...Option) *Decoder {
+	d := &Decoder{
+		timeLayout:   time.RFC3339,
+		timeLocation: time.UTC,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Decode takes the given sources and try to fill the fields of the given struct.
+func (d *Decoder) Decode(sources []Source, obj interface{}) error {
+	return d.DecodeContext(context.Background(), sources, obj)
+}
+
+// DecodeContext is like Decode but passes ctx to the sources with GetContext.
+// It stops with the error of ctx as soon as ctx is done, which is checked
+// before every field.
+func (d *Decoder) DecodeContext(ctx context.Context, sources []Source, obj interface{}) error {
+	valueOf, err := target(obj)
+	if err != nil {
+		return err
+	}
+
+	p, err := d.planOf(valueOf.Type())
+	if err != nil {
+		return err
+	}
+
+	var (
+		dec      = decoding{Decoder: d, ctx: ctx, sources: sources}
+		prefixes = dec.sources.prefixes()
+	)
+	if d.workers > 0 || dec.sources.batched() {
+		dec.prefetch(p, prefixes)
+	}
+	if _, err := dec.fill(p, valueOf, prefixes); err != nil {
+		return err
+	}
+
+	if len(dec.errs) > 0 {
+		return dec.errs
+	}
+	return nil
+}
+
+// target returns the struct the given obj points to. Nil pointers in between,
+// e.g. of a **T, are allocated.
+func target(obj interface{}) (reflect.Value, error) {
+	if obj == nil {
+		return reflect.Value{}, ErrNilTarget
+	}
+
+	valueOf := reflect.ValueOf(obj)
+	if valueOf.Kind() != reflect.Ptr {
+		return reflect.Value{}, fmt.Errorf("%w: %s", ErrNotPointer, valueOf.Type())
+	}
+	if valueOf.IsNil() {
+		return reflect.Value{}, fmt.Errorf("%w: %s", ErrTypedNil, valueOf.Type())
+	}
+
+	// check the type first to leave invalid targets untouched
+	t := valueOf.Type()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%w: %s", ErrNotStruct, t)
+	}
+
+	for valueOf.Kind() == reflect.Ptr {
+		if valueOf.IsNil() {
+			valueOf.Set(reflect.New(valueOf.Type().Elem()))
+		}
+		valueOf = valueOf.Elem()
+	}
+	return valueOf, nil
+}
+
+// BindWith returns a T filled by the given decoder from the sources. T has to
+// be a struct or a pointer to a struct, which is allocated. Otherwise an error
+// matching ErrNotStruct is returned. If the decoding fails, the value holds the
+// fields filled so far.
+func BindWith[T any](d *Decoder, sources []Source) (T, error) {
+	var v T
+
+	t := reflect.TypeOf(&v).Elem()
+	if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+		ptr := reflect.New(t.Elem())
+		reflect.ValueOf(&v).Elem().Set(ptr)
+		return v, d.Decode(sources, ptr.Interface())
+	}
+
+	if t.Kind() != reflect.Struct {
+		return v, fmt.Errorf("%w: %s", ErrNotStruct, t)
+	}
+	return v, d.Decode(sources, &v)
+}
+
+// decoding holds the state of a single Decode call.
+type decoding struct {
+	*Decoder
+	ctx     context.Context
+	sources Sources
+	errs    Errors
+
+	// fetched holds the prefetched values per source.
+	fetched []map[string]fetched
+
+	// missed collects the missing required fields of a nested struct which
+	// isn't allocated yet, it is nil otherwise.
+	missed *[]Error
+}
+
+// fail either collects the error or returns it to stop the decoding.
+func (dec *decoding) fail(err Error) error {
+	if !dec.collectErrors {
+		return err
+	}
+	dec.errs = append(dec.errs, err)
+	return nil
+}
+
+// fill sets the fields of the struct valueOf and reports whether at least one
+// of them received a value.
+//
+// prefixes holds the prefix of the field names per source, it is nil if none
+// of the sources has one.
+func (dec *decoding) fill(p *plan, valueOf reflect.Value, prefixes []string) (bool, error) {
+	var (
+		filled bool
+		b      = p.bind(dec.sources)
+	)
+	for i, field := range p.fields {
+		if err := dec.ctx.Err(); err != nil {
+			return filled, err
+		}
+
+		var (
+			property = valueOf.Field(field.index)
+			tags     = b[i]
+		)
+
+		if field.nested != nil && !tags.tagged {
+			ok, err := dec.fillNested(field, property, prefixes)
+			if err != nil {
+				return filled, err
+			}
+			filled = filled || ok
+			continue
+		}
+
+		// fields without a tag of the sources only take their default, their
+		// other tags may belong to other packages
+		if !tags.tagged && field.defaults == nil {
+			continue
+		}
+
+		if field.invalid != nil {
+			if err := dec.fail(*field.invalid); err != nil {
+				return filled, err
+			}
+			continue
+		}
+
+		ok, err := dec.fillField(field, tags, property, prefixes)
+		if err != nil {
+			return filled, err
+		}
+		filled = filled || ok
+	}
+	return filled, nil
+}
+
+// fillField sets a single field from the sources in their given order and
+// reports whether one of them provided a value. The default value of the field
+// is only used if none did, without one a required field fails.
+func (dec *decoding) fillField(field fieldPlan, tags fieldTags, property reflect.Value, prefixes []string) (bool, error) {
+	var filled, provided bool
+	for i, source := range dec.sources {
+		tagValue, ok := tags.key(i, prefixes)
+		if !ok {
+			continue
+		}
+
+		v, err := dec.get(i, tagValue)
+		if err != nil {
+			var values []string
+			if v != nil {
+				values = v.values()
+			}
+
+			provided = true
+			if err = dec.fail(newError(ErrSource, tagValue, source.Tag, values, err)); err != nil {
+				return filled, err
+			}
+			continue
+		}
+
+		if v == nil {
+			continue
+		}
+
+		values, err := assign(field, property, v)
+		if len(values) == 0 {
+			continue
+		}
+
+		provided = true
+		if err != nil {
+			if err = dec.fail(newError(ErrConversion, tagValue, source.Tag, values, err)); err != nil {
+				return filled, err
+			}
+			continue
+		}
+		filled = true
+	}
+
+	if provided {
+		return filled, nil
+	}
+
+	if field.defaults != nil {
+		if err := field.set(property, field.defaults); err != nil {
+			return filled, dec.fail(newError(ErrInvalidTag, field.name, defaultTag, field.defaults, err))
+		}
+		return filled, nil
+	}
+
+	if field.required {
+		return filled, dec.require(dec.missing(field, tags, prefixes))
+	}
+	return filled, nil
+}
+
+// get returns the value of the field from the i-th source, which was either
+// prefetched or is got now.
+func (dec *decoding) get(i int, field string) (Valuer, error) {
+	if dec.fetched != nil {
+		if f, ok := dec.fetched[i][field]; ok {
+			return f.v, f.err
+		}
+	}
+	return dec.sources[i].get(dec.ctx, field)
+}
+
+// assign converts the values of v and assigns them to the field. It returns
+// the values of v which are empty if v didn't provide any.
+func assign(field fieldPlan, property reflect.Value, v Valuer) ([]string, error) {
+	values := v.values()
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	if kv, ok := v.(keyValues); ok && field.setPairs != nil {
+		if field.split != "" {
+			split := make(keyValues, len(kv))
+			for k, vs := range kv {
+				items, err := splitValues(vs, field.split)
+				if err != nil {
+					return values, err
+				}
+				split[k] = items
+			}
+			kv = split
+		}
+		return values, field.setPairs(property, kv)
+	}
+
+	if sep := field.separator(v); sep != "" {
+		items, err := splitValues(values, sep)
+		if err != nil {
+			return values, err
+		}
+		values = items
+	}
+
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return values, field.set(property, values)
+}
+
+// missing creates the error of a required field which didn't receive a value.
+// It names the field after the first source it is tagged for.
+func (dec *decoding) missing(field fieldPlan, tags fieldTags, prefixes []string) Error {
+	for i, source := range dec.sources {
+		if tagValue, ok := tags.key(i, prefixes); ok {
+			return newError(nil, tagValue, source.Tag, nil, ErrMissingValue)
+		}
+	}
+	return newError(nil, field.name, "", nil, ErrMissingValue)
+}
+
+// require fails with a missing required field. Within a nested struct which
+// isn't allocated yet, it is only reported once the struct is.
+func (dec *decoding) require(err Error) error {
+	if dec.missed != nil {
+		*dec.missed = append(*dec.missed, err)
+		return nil
+	}
+	return dec.fail(err)
+}
+
+// fillNested fills the fields of a nested struct. A nil pointer to the nested
+// struct is only allocated if one of its fields received a value.
+func (dec *decoding) fillNested(field fieldPlan, property reflect.Value, prefixes []string) (bool, error) {
+	if !field.anonymous {
+		prefixes = dec.nest(field, prefixes)
+	}
+
+	if !field.ptr {
+		return dec.fill(field.nested, property, prefixes)
+	}
+
+	if !property.IsNil() {
+		return dec.fill(field.nested, property.Elem(), prefixes)
+	}
+
+	// the required fields of an optional struct which none of the sources
+	// provides aren't missing
+	var (
+		ptr    = reflect.New(property.Type().Elem())
+		outer  = dec.missed
+		missed []Error
+	)
+	dec.missed = &missed
+	filled, err := dec.fill(field.nested, ptr.Elem(), prefixes)
+	dec.missed = outer
+	if err != nil || !filled {
+		return false, err
+	}
+
+	property.Set(ptr)
+	for _, e := range missed {
+		if err := dec.require(e); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// nest returns the prefixes of the fields of the nested struct field. Sources
+// without Nest keep the prefix of the current struct.
+func (dec *decoding) nest(field fieldPlan, prefixes []string) []string {
+	var nested []string
+	for i, source := range dec.sources {
+		if source.Nest == nil {
+			continue
+		}
+
+		if nested == nil {
+			nested = make([]string, len(dec.sources))
+			copy(nested, prefixes)
+		}
+		nested[i] = source.Nest(nested[i], field.name)
+	}
+
+	if nested == nil {
+		return prefixes
+	}
+	return nested
+}
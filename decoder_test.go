@@ -0,0 +1,409 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeStopsAtFirstError(t *testing.T) {
+
+	var s struct {
+		Int   int    `foo:"int"`
+		Bool  bool   `foo:"bool"`
+		Valid string `foo:"valid"`
+	}
+
+	var calls []string
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				calls = append(calls, field)
+				return Value("invalid"), nil
+			},
+		},
+	}
+
+	err := NewDecoder().Decode(sources, &s)
+	assert.Error(t, err)
+
+	var parsedErr Error
+	assert.True(t, errors.As(err, &parsedErr))
+	assert.Equal(t, "int", parsedErr.Field)
+	assert.Equal(t, []string{"int"}, calls)
+}
+
+func TestDecodeCollectErrors(t *testing.T) {
+
+	var s struct {
+		Int    int    `foo:"int"`
+		Bool   bool   `foo:"bool" john:"bool"`
+		Valid  string `foo:"valid"`
+		Failed string `foo:"failed"`
+	}
+
+	sourceErr := errors.New("test error")
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "valid":
+					return Value("helloworld"), nil
+				case "failed":
+					return nil, sourceErr
+				}
+				return Value("invalid"), nil
+			},
+		},
+		{
+			Tag: "john",
+			Get: func(field string) (Valuer, error) {
+				return Value("true"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+	assert.Error(t, err)
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+
+	assert.Equal(t, "int", errs[0].Field)
+	assert.Equal(t, "foo", errs[0].Source)
+	assert.Equal(t, "invalid", errs[0].Value)
+
+	assert.Equal(t, "bool", errs[1].Field)
+	assert.Equal(t, "foo", errs[1].Source)
+	assert.Equal(t, "invalid", errs[1].Value)
+
+	assert.Equal(t, "failed", errs[2].Field)
+	assert.Equal(t, sourceErr, errs[2].InnerError)
+
+	var parsedErr Error
+	assert.True(t, errors.As(err, &parsedErr))
+	assert.Equal(t, "int", parsedErr.Field)
+
+	assert.True(t, errors.Is(err, errs[2]))
+
+	assert.Equal(t, errs[0].Error()+"; "+errs[1].Error()+"; "+errs[2].Error(), err.Error())
+
+	assert.True(t, s.Bool)
+	assert.Equal(t, "helloworld", s.Valid)
+}
+
+func TestDecodeCollectErrorsWithoutErrors(t *testing.T) {
+
+	var s struct {
+		String string `foo:"bar"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("helloworld"), nil
+			},
+		},
+	}
+
+	assert.NoError(t, NewDecoder(CollectErrors()).Decode(sources, &s))
+	assert.Equal(t, "helloworld", s.String)
+}
+
+func TestDecodeConverter(t *testing.T) {
+
+	var s struct {
+		URL     url.URL               `foo:"url"`
+		Pointer *url.URL              `foo:"url"`
+		URLs    []url.URL             `foo:"urls"`
+		Hosts   map[string]url.URL    `foo:"hosts"`
+		Addr    netip.Addr            `foo:"addr"`
+		Joined  fmt.Stringer          `foo:"urls"`
+		Plain   map[string]netip.Addr `foo:"none"`
+	}
+
+	decoder := NewDecoder(
+		Converter(func(values []string) (url.URL, error) {
+			u, err := url.Parse(values[0])
+			if err != nil {
+				return url.URL{}, err
+			}
+			return *u, nil
+		}),
+		Converter(func(values []string) (netip.Addr, error) {
+			return netip.AddrFrom4([4]byte{127, 0, 0, 1}), nil
+		}),
+		Converter(func(values []string) (fmt.Stringer, error) {
+			return &strings.Builder{}, nil
+		}),
+	)
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "url":
+					return Value("https://example.com/path"), nil
+				case "urls":
+					return Values([]string{"https://a.com", "https://b.com"}), nil
+				case "hosts":
+					return Value("a=https://a.com"), nil
+				case "addr":
+					return Value("10.0.0.1"), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, decoder.Decode(sources, &s))
+	assert.Equal(t, "https://example.com/path", s.URL.String())
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, "https://example.com/path", s.Pointer.String())
+	assert.Len(t, s.URLs, 2)
+	assert.Equal(t, "https://b.com", s.URLs[1].String())
+	assert.Equal(t, "a.com", s.Hosts["a"].Host)
+	assert.Equal(t, netip.AddrFrom4([4]byte{127, 0, 0, 1}), s.Addr)
+	assert.NotNil(t, s.Joined)
+
+	// converters are scoped to their decoder
+	var other struct {
+		URL url.URL `foo:"url"`
+	}
+	assert.Error(t, NewDecoder().Decode(sources, &other))
+}
+
+func TestDecodeConverterWithError(t *testing.T) {
+
+	var s struct {
+		URL *url.URL `foo:"url"`
+	}
+
+	convertErr := errors.New("test error")
+	decoder := NewDecoder(
+		Converter(func(values []string) (url.URL, error) {
+			return url.URL{}, convertErr
+		}),
+	)
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("https://example.com"), nil
+			},
+		},
+	}
+
+	err := decoder.Decode(sources, &s)
+	assert.True(t, errors.Is(err, ErrConversion))
+	assert.True(t, errors.Is(err, convertErr))
+
+	hErr, ok := FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com", hErr.Value)
+	assert.Nil(t, s.URL)
+}
+
+type bindRequest struct {
+	ID    int      `foo:"id"`
+	Names []string `foo:"names"`
+}
+
+func bindSources() []Source {
+	return []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "id":
+					return Value("42"), nil
+				case "names":
+					return Values([]string{"a", "b"}), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+}
+
+func TestBind(t *testing.T) {
+
+	req, err := Bind[bindRequest](bindSources())
+	assert.NoError(t, err)
+	assert.Equal(t, bindRequest{ID: 42, Names: []string{"a", "b"}}, req)
+
+	ptr, err := Bind[*bindRequest](bindSources())
+	assert.NoError(t, err)
+	assert.NotNil(t, ptr)
+	assert.Equal(t, 42, ptr.ID)
+
+	empty, err := Bind[*bindRequest](nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, empty)
+}
+
+func TestBindWith(t *testing.T) {
+
+	decoder := NewDecoder(CollectErrors())
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "id" {
+					return Value("abc"), nil
+				}
+				return Value("x"), nil
+			},
+		},
+	}
+
+	req, err := BindWith[bindRequest](decoder, sources)
+	assert.True(t, errors.Is(err, ErrConversion))
+	assert.Equal(t, []string{"x"}, req.Names)
+}
+
+func TestBindNotStruct(t *testing.T) {
+
+	_, err := Bind[int](bindSources())
+	assert.True(t, errors.Is(err, ErrNotStruct))
+	assert.Contains(t, err.Error(), "int")
+
+	_, err = Bind[**bindRequest](bindSources())
+	assert.True(t, errors.Is(err, ErrNotStruct))
+
+	_, err = Bind[interface{}](nil)
+	assert.True(t, errors.Is(err, ErrNotStruct))
+
+	_, err = Bind[[]bindRequest](bindSources())
+	assert.True(t, errors.Is(err, ErrNotStruct))
+}
+
+func TestDecodeNotStruct(t *testing.T) {
+
+	var i int
+	err := NewDecoder().Decode(bindSources(), &i)
+	assert.True(t, errors.Is(err, ErrNotStruct))
+}
+
+type contextKey struct{}
+
+func TestDecodeContext(t *testing.T) {
+
+	var s struct {
+		Foo string `foo:"foo"`
+		Bar string `bar:"bar"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetContext: func(ctx context.Context, field string) (Valuer, error) {
+				return Value(ctx.Value(contextKey{}).(string)), nil
+			},
+		},
+		{
+			Tag: "bar",
+			Get: func(field string) (Valuer, error) {
+				return Value("plain"), nil
+			},
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "from context")
+	assert.NoError(t, From(sources).ToContext(ctx, &s))
+	assert.Equal(t, "from context", s.Foo)
+	assert.Equal(t, "plain", s.Bar)
+}
+
+func TestDecodeContextCanceled(t *testing.T) {
+
+	var s struct {
+		First  string `foo:"first"`
+		Nested struct {
+			Second string `foo:"second"`
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var fields []string
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetContext: func(ctx context.Context, field string) (Valuer, error) {
+				fields = append(fields, field)
+				cancel()
+				return Value("value"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).DecodeContext(ctx, sources, &s)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, []string{"first"}, fields)
+	assert.Equal(t, "value", s.First)
+	assert.Empty(t, s.Nested.Second)
+
+	// a done context stops before the first field
+	fields = nil
+	assert.True(t, errors.Is(From(sources).ToContext(ctx, &s), context.Canceled))
+	assert.Empty(t, fields)
+}
+
+func TestDecodeContextDeadline(t *testing.T) {
+
+	var s struct {
+		Foo string `foo:"foo"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetContext: func(ctx context.Context, field string) (Valuer, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := From(sources).ToContext(ctx, &s)
+	assert.True(t, errors.Is(err, ErrSource))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+}
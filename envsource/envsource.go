@@ -0,0 +1,127 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package envsource provides a handgover source for environment variables.
+//
+//	type Config struct {
+//		Port  int      `env:"PORT"`
+//		Hosts []string `env:"HOSTS"`
+//		DB    struct {
+//			URL string `env:"URL"`
+//		}
+//	}
+//
+//	var config Config
+//	err := envsource.Bind(&config, envsource.Prefix("ORDERS_"))
+//
+// fills Port from ORDERS_PORT, splits ORDERS_HOSTS at commas and takes the URL
+// of the nested DB struct from ORDERS_DB_URL.
+package envsource
+
+import (
+	"os"
+	"unicode"
+
+	"github.com/newstore-oss/handgover"
+)
+
+// Tag of the environment variable source.
+const Tag = "env"
+
+// DefaultSeparator splits the values of slice fields.
+const DefaultSeparator = ","
+
+type options struct {
+	prefix    string
+	separator string
+}
+
+// Option configures the environment variable source.
+type Option func(*options)
+
+// Prefix is put in front of the name of every environment variable.
+func Prefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// Separator splits the values of slice fields, it defaults to
+// DefaultSeparator.
+func Separator(separator string) Option {
+	return func(o *options) {
+		o.separator = separator
+	}
+}
+
+// Source returns a source for environment variables.
+//
+// An unset variable doesn't provide a value, while an empty one provides the
+// empty string. Fields of nested structs are looked up with the name of the
+// struct field in upper snake case as additional prefix, e.g. DB_URL for the
+// field URL of the nested struct DB.
+func Source(opts ...Option) handgover.Source {
+	o := options{separator: DefaultSeparator}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return handgover.Source{
+		Tag:    Tag,
+		Prefix: o.prefix,
+		Get: func(field string) (handgover.Valuer, error) {
+			v, ok := os.LookupEnv(field)
+			if !ok {
+				return nil, nil
+			}
+			return handgover.Split(v, o.separator), nil
+		},
+		Nest: func(prefix, name string) string {
+			return prefix + upperSnakeCase(name) + "_"
+		},
+	}
+}
+
+// Bind fills v with the values of the environment variables.
+func Bind(v interface{}, opts ...Option) error {
+	return handgover.From([]handgover.Source{Source(opts...)}).To(v)
+}
+
+// upperSnakeCase converts a Go identifier like HTTPServer to HTTP_SERVER.
+func upperSnakeCase(name string) string {
+	var (
+		runes = []rune(name)
+		snake = make([]rune, 0, len(runes)+2)
+	)
+
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			var (
+				prev      = runes[i-1]
+				nextLower = i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			)
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				snake = append(snake, '_')
+			}
+		}
+		snake = append(snake, unicode.ToUpper(r))
+	}
+	return string(snake)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package envsource
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/newstore-oss/handgover"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBind(t *testing.T) {
+
+	t.Setenv("ORDERS_PORT", "8080")
+	t.Setenv("ORDERS_NAME", "")
+	t.Setenv("ORDERS_HOSTS", "a,b,c")
+	t.Setenv("ORDERS_TAGS", "a,b")
+	t.Setenv("ORDERS_DB_URL", "postgres://localhost")
+	t.Setenv("ORDERS_DB_CONN_POOL_TIMEOUT", "5s")
+	t.Setenv("ORDERS_HTTP_SERVER_PORT", "80")
+	t.Setenv("ORDERS_LEVEL", "debug")
+
+	var s struct {
+		Port    int      `env:"PORT"`
+		Name    string   `env:"NAME" default:"orders"`
+		Missing string   `env:"MISSING" default:"missing"`
+		Hosts   []string `env:"HOSTS"`
+		Tags    string   `env:"TAGS"`
+		DB      struct {
+			URL      string `env:"URL"`
+			ConnPool *struct {
+				Timeout time.Duration `env:"TIMEOUT"`
+			}
+		}
+		HTTPServer struct {
+			Port int `env:"PORT"`
+		}
+		Logging
+	}
+
+	assert.NoError(t, Bind(&s, Prefix("ORDERS_")))
+	assert.Equal(t, 8080, s.Port)
+	assert.Equal(t, "", s.Name)
+	assert.Equal(t, "missing", s.Missing)
+	assert.Equal(t, []string{"a", "b", "c"}, s.Hosts)
+	assert.Equal(t, "a,b", s.Tags)
+	assert.Equal(t, "postgres://localhost", s.DB.URL)
+	assert.NotNil(t, s.DB.ConnPool)
+	assert.Equal(t, 5*time.Second, s.DB.ConnPool.Timeout)
+	assert.Equal(t, 80, s.HTTPServer.Port)
+	assert.Equal(t, "debug", s.Level)
+}
+
+type Logging struct {
+	Level string `env:"LEVEL"`
+}
+
+func TestBindWithSeparator(t *testing.T) {
+
+	t.Setenv("IDS", "1;2;3")
+	t.Setenv("EMPTY", "")
+
+	var s struct {
+		IDs   []int `env:"IDS"`
+		Empty []int `env:"EMPTY"`
+	}
+
+	assert.NoError(t, Bind(&s, Separator(";")))
+	assert.Equal(t, []int{1, 2, 3}, s.IDs)
+	assert.Nil(t, s.Empty)
+}
+
+func TestBindWithQuotedItems(t *testing.T) {
+
+	t.Setenv("HOSTS", `"a,b",c`)
+	t.Setenv("LIST", "a;b,c")
+
+	var s struct {
+		Hosts []string `env:"HOSTS"`
+		List  []string `env:"LIST" split:";"`
+		Whole []string `env:"LIST" split:""`
+	}
+
+	assert.NoError(t, Bind(&s))
+	assert.Equal(t, []string{"a,b", "c"}, s.Hosts)
+	// the split tag replaces the separator of the source
+	assert.Equal(t, []string{"a", "b,c"}, s.List)
+	assert.Equal(t, []string{"a;b,c"}, s.Whole)
+}
+
+func TestBindWithInvalidValue(t *testing.T) {
+
+	t.Setenv("ORDERS_DB_PORT", "abc")
+
+	var s struct {
+		DB struct {
+			Port int `env:"PORT"`
+		}
+	}
+
+	err := Bind(&s, Prefix("ORDERS_"))
+	assert.True(t, errors.Is(err, handgover.ErrConversion))
+
+	hErr, ok := handgover.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "ORDERS_DB_PORT", hErr.Field)
+	assert.Equal(t, Tag, hErr.Source)
+	assert.Equal(t, "abc", hErr.Value)
+}
+
+func TestUpperSnakeCase(t *testing.T) {
+
+	cases := map[string]string{
+		"DB":         "DB",
+		"Port":       "PORT",
+		"ConnPool":   "CONN_POOL",
+		"HTTPServer": "HTTP_SERVER",
+		"ServerHTTP": "SERVER_HTTP",
+		"OAuth2":     "O_AUTH2",
+		"V2Config":   "V2_CONFIG",
+	}
+
+	for name, expected := range cases {
+		assert.Equal(t, expected, upperSnakeCase(name), name)
+	}
+}
@@ -21,30 +21,83 @@ package handgover
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 )
 
+var (
+	// ErrNilTarget is returned if the given struct to fill is nil.
+	ErrNilTarget = errors.New("given struct to fill is nil")
+
+	// ErrTypedNil is returned if the given target is a nil pointer.
+	ErrTypedNil = errors.New("given pointer to fill is nil")
+
+	// ErrNotPointer is returned if the given target isn't a pointer, so its
+	// fields can't be set.
+	ErrNotPointer = errors.New("given target is not a pointer")
+
+	// ErrNotStruct is returned if the given target isn't a struct or a
+	// pointer to a struct.
+	ErrNotStruct = errors.New("given target is not a struct")
+
+	// ErrUnsupportedKind is wrapped by the inner error of a field whose type
+	// can't be filled.
+	ErrUnsupportedKind = errors.New("unsupported property kind")
+
+	// ErrSource is matched by an Error if the source failed to get the value.
+	ErrSource = errors.New("source failed")
+
+	// ErrConversion is matched by an Error if the value of the source couldn't
+	// be converted to the type of the field.
+	ErrConversion = errors.New("conversion failed")
+
+	// ErrInvalidTag is matched by an Error if a struct tag of the field to fill
+	// is invalid, e.g. a default value which can't be converted. In that case
+	// Error.Field holds the name of the struct field and Error.Source the tag.
+	ErrInvalidTag = errors.New("invalid struct tag")
+
+	// ErrMissingValue is the inner error of an Error if a required field
+	// didn't receive a value from any of the sources.
+	ErrMissingValue = errors.New("missing required value")
+)
+
+// Error describes why a single field couldn't be filled.
+//
+// Besides the inner error it matches the sentinel of its failure class
+// (e.g. ErrSource or ErrConversion) with errors.Is.
 type Error struct {
 	Field      string
 	Source     string
 	Value      string
 	InnerError error
+
+	class error
 }
 
-func newError(field, source string, values []string, err error) Error {
+// FromError returns the first Error found in the chain of err.
+func FromError(err error) (Error, bool) {
+	var e Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
+
+func newError(class error, field, source string, values []string, err error) Error {
 
 	e := Error{
 		Field:      field,
 		Source:     source,
 		InnerError: err,
+		class:      class,
 	}
 
 	switch ie := e.InnerError.(type) {
 	case *strconv.NumError:
 		e.Value = ie.Num
+	case *parseError:
+		e.Value = ie.value
 	case *time.ParseError:
 		e.Value = ie.Value
 	case *json.UnsupportedValueError:
@@ -63,6 +116,65 @@ func newError(field, source string, values []string, err error) Error {
 	return e
 }
 
+// parseError is returned by the conversions which don't use the strconv
+// package, it names what the value couldn't be parsed as.
+type parseError struct {
+	value string
+	as    string
+	err   error
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("parsing %q as %s: %s", e.value, e.as, e.err)
+}
+
+// Unwrap returns the cause, e.g. strconv.ErrSyntax.
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
 func (te Error) Error() string {
 	return fmt.Sprintf("failed to set field %q from source %q: %s", te.Field, te.Source, te.InnerError)
 }
+
+// Unwrap returns the inner error.
+func (te Error) Unwrap() error {
+	return te.InnerError
+}
+
+// Is reports whether target is the sentinel of the failure class.
+func (te Error) Is(target error) bool {
+	return te.class != nil && te.class == target
+}
+
+// Errors is returned by a Decoder with the CollectErrors option and contains
+// every field which failed.
+type Errors []Error
+
+func (errs Errors) Error() string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Is reports whether one of the errors matches target.
+func (errs Errors) Is(target error) bool {
+	for _, err := range errs {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error that matches target, see errors.As.
+func (errs Errors) As(target interface{}) bool {
+	for _, err := range errs {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFromError(t *testing.T) {
+
+	var s struct {
+		Int int `foo:"bar"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("abc"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+
+	hErr, ok := FromError(fmt.Errorf("wrapped: %w", err))
+	assert.True(t, ok)
+	assert.Equal(t, "bar", hErr.Field)
+	assert.Equal(t, "foo", hErr.Source)
+	assert.Equal(t, "abc", hErr.Value)
+
+	_, ok = FromError(errors.New("test error"))
+	assert.False(t, ok)
+
+	_, ok = FromError(nil)
+	assert.False(t, ok)
+}
+
+func TestErrorUnwrap(t *testing.T) {
+
+	var s struct {
+		Int int `foo:"bar"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("abc"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+
+	var numErr *strconv.NumError
+	assert.True(t, errors.As(err, &numErr))
+	assert.Equal(t, "abc", numErr.Num)
+}
+
+func TestErrorClasses(t *testing.T) {
+
+	var s struct {
+		Int     int         `foo:"int"`
+		Chan    chan string `foo:"chan"`
+		Failing string      `foo:"failing"`
+	}
+
+	sourceErr := errors.New("test error")
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "failing" {
+					return nil, sourceErr
+				}
+				return Value("abc"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+
+	assert.True(t, errors.Is(errs[0], ErrConversion))
+	assert.False(t, errors.Is(errs[0], ErrSource))
+	assert.False(t, errors.Is(errs[0], ErrUnsupportedKind))
+
+	assert.True(t, errors.Is(errs[1], ErrConversion))
+	assert.True(t, errors.Is(errs[1], ErrUnsupportedKind))
+
+	assert.True(t, errors.Is(errs[2], ErrSource))
+	assert.True(t, errors.Is(errs[2], sourceErr))
+	assert.False(t, errors.Is(errs[2], ErrConversion))
+
+	assert.True(t, errors.Is(err, ErrSource))
+	assert.True(t, errors.Is(err, strconv.ErrSyntax))
+}
+
+func TestErrNilTarget(t *testing.T) {
+	assert.True(t, errors.Is(From([]Source{{Tag: "foo"}}).To(nil), ErrNilTarget))
+}
+
+func TestInvalidTargets(t *testing.T) {
+
+	type target struct {
+		Foo string `foo:"foo"`
+	}
+
+	var (
+		nilStruct *target
+		i         int
+		pi        = &i
+	)
+
+	tests := []struct {
+		name string
+		obj  interface{}
+		err  error
+	}{
+		{"typed nil", nilStruct, ErrTypedNil},
+		{"typed nil of pointer", (**target)(nil), ErrTypedNil},
+		{"struct", target{}, ErrNotPointer},
+		{"int", 42, ErrNotPointer},
+		{"pointer to int", &i, ErrNotStruct},
+		{"pointer to pointer to int", &pi, ErrNotStruct},
+		{"pointer to slice", &[]target{}, ErrNotStruct},
+		{"pointer to interface", new(interface{}), ErrNotStruct},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := From([]Source{{Tag: "foo"}}).To(tt.obj)
+			assert.True(t, errors.Is(err, tt.err), err)
+
+			// the target is validated even without sources
+			assert.True(t, errors.Is(From(nil).To(tt.obj), tt.err))
+		})
+	}
+}
+
+func TestInvalidTargetIsNotAllocated(t *testing.T) {
+
+	target := new(*int)
+
+	err := From([]Source{{Tag: "foo"}}).To(target)
+	assert.True(t, errors.Is(err, ErrNotStruct))
+	assert.Nil(t, *target)
+}
+
+func TestAllocateNilPointerTarget(t *testing.T) {
+
+	var s *struct {
+		Foo string `foo:"foo"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("bar"), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.NotNil(t, s)
+	assert.Equal(t, "bar", s.Foo)
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fuzzEmbedded struct {
+	Embedded string `foo:"embedded"`
+}
+
+type fuzzNested struct {
+	Value int    `foo:"value"`
+	Text  string `foo:"text"`
+}
+
+type fuzzNode struct {
+	Name string    `foo:"name"`
+	Next *fuzzNode `foo:"next"`
+}
+
+type fuzzTarget struct {
+	fuzzEmbedded
+
+	String     string                 `foo:"string"`
+	Int8       int8                   `foo:"int8"`
+	Uint       uint                   `foo:"uint"`
+	Float32    float32                `foo:"float32"`
+	Complex    complex64              `foo:"complex"`
+	Bool       *bool                  `foo:"bool"`
+	Duration   time.Duration          `foo:"duration"`
+	Time       time.Time              `foo:"time" layout:"unixmilli"`
+	Bytes      []byte                 `foo:"bytes" encoding:"base64"`
+	Hex        [4]byte                `foo:"hex" encoding:"hex"`
+	Array      [2]int                 `foo:"array"`
+	Slice      []*uint16              `foo:"slice"`
+	Split      []string               `foo:"split" split:";"`
+	Map        map[string]int         `foo:"map"`
+	IntMap     map[int][]float64      `foo:"intmap"`
+	Any        interface{}            `foo:"any"`
+	Anys       map[string]interface{} `foo:"anys"`
+	Stringer   fmt.Stringer           `foo:"stringer"`
+	Chan       chan int               `foo:"chan"`
+	Func       func()                 `foo:"func"`
+	IP         net.IP                 `foo:"ip"`
+	BigInt     *big.Int               `foo:"bigint"`
+	BigFloat   *big.Float             `foo:"bigfloat"`
+	Rat        big.Rat                `foo:"rat"`
+	JSON       fuzzNested             `foo:"json"`
+	Nested     fuzzNested
+	Pointer    *fuzzNested
+	Node       fuzzNode
+	Defaulted  int `foo:"defaulted" default:"1"`
+	Required   int `foo:"required" required:"true"`
+	unexported string
+}
+
+// fuzzTargets returns valid and invalid targets for To.
+func fuzzTargets() []func() interface{} {
+	return []func() interface{}{
+		func() interface{} { return nil },
+		func() interface{} { return &fuzzTarget{} },
+		func() interface{} { return fuzzTarget{} },
+		func() interface{} { return (*fuzzTarget)(nil) },
+		func() interface{} { return new(*fuzzTarget) },
+		func() interface{} { return &fuzzNode{} },
+		func() interface{} { return new(int) },
+		func() interface{} { return new(interface{}) },
+		func() interface{} { return &[]fuzzTarget{} },
+		func() interface{} { return map[string]string{} },
+		func() interface{} { return "foo" },
+	}
+}
+
+// fuzzValuers returns the valuers a source may return for value.
+func fuzzValuers(value string) []Valuer {
+	return []Valuer{
+		nil,
+		Value(value),
+		Values([]string{value, value}),
+		Values(nil),
+		Split(value, ","),
+		KeyValues(map[string][]string{value: {value}}),
+	}
+}
+
+var fuzzDecoders = []*Decoder{
+	NewDecoder(),
+	NewDecoder(CollectErrors(), InferTypes(), StrictArrays()),
+	NewDecoder(SplitSeparator(","), TimeLayout("date"), TimeLocation(nil)),
+}
+
+// decodeArbitrary decodes value into one of the targets and reports whether
+// it did so without a panic.
+func decodeArbitrary(t *testing.T, target, valuer, decoder uint8, value string) bool {
+	var (
+		targets = fuzzTargets()
+		valuers = fuzzValuers(value)
+		obj     = targets[int(target)%len(targets)]()
+		v       = valuers[int(valuer)%len(valuers)]
+		d       = fuzzDecoders[int(decoder)%len(fuzzDecoders)]
+		nest    = func(prefix, name string) string { return prefix + name + "." }
+		sources = []Source{
+			{Tag: "foo", Get: func(string) (Valuer, error) { return v, nil }, Nest: nest},
+			{Tag: "foo", Get: func(string) (Valuer, error) { return Value(value), nil }},
+		}
+	)
+
+	return assert.NotPanics(t, func() {
+		_ = d.Decode(sources, obj)
+	}, "target %T, valuer %#v", obj, v)
+}
+
+func FuzzTo(f *testing.F) {
+	for _, value := range []string{"", "1", "-1", "abc", "a=b", "1,2,3", "0x10", "1e400", "\"a;b\";c", "true", "2024-05-01"} {
+		f.Add(uint8(1), uint8(1), uint8(0), value)
+		f.Add(uint8(1), uint8(2), uint8(1), value)
+		f.Add(uint8(1), uint8(4), uint8(2), value)
+		f.Add(uint8(1), uint8(5), uint8(1), value)
+	}
+	for target := range fuzzTargets() {
+		f.Add(uint8(target), uint8(1), uint8(0), "1")
+	}
+
+	f.Fuzz(func(t *testing.T, target, valuer, decoder uint8, value string) {
+		decodeArbitrary(t, target, valuer, decoder, value)
+	})
+}
+
+func TestToNeverPanics(t *testing.T) {
+
+	property := func(target, valuer, decoder uint8, value string) bool {
+		return decodeArbitrary(t, target, valuer, decoder, value)
+	}
+
+	assert.NoError(t, quick.Check(property, &quick.Config{MaxCount: 2000}))
+}
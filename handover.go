@@ -20,77 +20,219 @@
 package handgover
 
 import (
+	"context"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 )
 
-func setValue(property reflect.Value, values ...string) error {
-	switch kind := property.Kind(); kind {
+// setter converts the given values and assigns the result to property.
+type setter func(property reflect.Value, values []string) error
+
+// format describes how the values of a field are formatted. It is defined by
+// the struct tags of the field.
+type format struct {
+	// layout of time.Time values, see newTimeSetter
+	layout string
+
+	// encoding of []byte and [N]byte values, see byteEncodings
+	encoding string
+}
+
+// Unmarshaler is implemented by types which convert the values of a source
+// themselves. In contrast to encoding.TextUnmarshaler it receives all values
+// of the source.
+type Unmarshaler interface {
+	UnmarshalValues(values []string) error
+}
+
+var (
+	timeType            = reflect.TypeOf(time.Time{})
+	durationType        = reflect.TypeOf(time.Duration(0))
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// implements reports whether a pointer to t implements the interface iface.
+// Pointers are excluded, their element is checked by the pointer setter.
+func implements(t, iface reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return false
+	}
+	return reflect.PointerTo(t).Implements(iface)
+}
+
+// newSetter resolves the conversion for the given type once, so it doesn't
+// have to be dispatched again every time a value gets assigned.
+func (d *Decoder) newSetter(t reflect.Type, f format) setter {
+	if set, ok := d.converters[t]; ok {
+		return set
+	}
+
+	if implements(t, unmarshalerType) {
+		return newUnmarshalerSetter(t)
+	}
+
+	switch t {
+	case timeType:
+		return d.newTimeSetter(f.layout)
+	case durationType:
+		return setDuration
+	}
+
+	if set := newNumberSetter(t); set != nil {
+		return set
+	}
+
+	if implements(t, textUnmarshalerType) {
+		return newTextUnmarshalerSetter(t)
+	}
+
+	switch kind := t.Kind(); kind {
 	case reflect.Ptr:
-		return setPointer(property, values)
+		return d.newPointerSetter(t, f)
 	case reflect.Slice:
-		return setSlice(property, values)
+		return d.newSliceSetter(t, f)
+	case reflect.Array:
+		return d.newArraySetter(t, f)
+	case reflect.Map:
+		return d.newMapSetter(t, f).set
 	case reflect.String:
-		return setString(property, values)
-	case reflect.Int:
-		return setInt(property, values)
-	case reflect.Int8:
-		return setInt(property, values)
-	case reflect.Int16:
-		return setInt(property, values)
-	case reflect.Int32:
-		return setInt(property, values)
-	case reflect.Int64:
-		return setInt(property, values)
-	case reflect.Uint:
-		return setUInt(property, values)
-	case reflect.Uint8:
-		return setUInt(property, values)
-	case reflect.Uint16:
-		return setUInt(property, values)
-	case reflect.Uint32:
-		return setUInt(property, values)
-	case reflect.Uint64:
-		return setUInt(property, values)
+		return setString
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return newIntSetter(t.Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return newUIntSetter(t.Bits())
 	case reflect.Bool:
-		return setBool(property, values)
+		return setBool
 	case reflect.Float32:
-		return setFloat32(property, values)
+		return setFloat32
 	case reflect.Float64:
-		return setFloat64(property, values)
+		return setFloat64
 	case reflect.Struct:
-		return setStruct(property, values)
+		return setStruct
+	case reflect.Interface:
+		if t.NumMethod() > 0 {
+			return newUnsupportedSetter(kind)
+		}
+		return d.newInterfaceSetter()
 	default:
-		return fmt.Errorf("unsupported property kind %q", kind)
+		return newUnsupportedSetter(kind)
 	}
 }
 
-func setPointer(property reflect.Value, values []string) error {
-	property.Set(reflect.New(property.Type().Elem()))
-	return setValue(property.Elem(), values...)
+func newUnsupportedSetter(kind reflect.Kind) setter {
+	return func(reflect.Value, []string) error {
+		return fmt.Errorf("%w %q", ErrUnsupportedKind, kind)
+	}
 }
 
-func setStruct(property reflect.Value, values []string) error {
-	switch property.Interface().(type) {
-	case time.Time:
-		t, err := time.Parse(time.RFC3339, values[0])
-		if err != nil {
+// newInterfaceSetter fills empty interfaces with a string for a single value
+// and a []string for multiple ones. With InferTypes the values are converted
+// to bool, int64 or float64 if possible instead, multiple ones to []interface{}.
+func (d *Decoder) newInterfaceSetter() setter {
+	if !d.inferTypes {
+		return func(property reflect.Value, values []string) error {
+			if len(values) == 1 {
+				property.Set(reflect.ValueOf(values[0]))
+				return nil
+			}
+			property.Set(reflect.ValueOf(append([]string(nil), values...)))
+			return nil
+		}
+	}
+
+	return func(property reflect.Value, values []string) error {
+		if len(values) == 1 {
+			property.Set(reflect.ValueOf(infer(values[0])))
+			return nil
+		}
+
+		inferred := make([]interface{}, len(values))
+		for i, v := range values {
+			inferred[i] = infer(v)
+		}
+		property.Set(reflect.ValueOf(inferred))
+		return nil
+	}
+}
+
+// infer converts value to the first type of bool, int64 and float64 it is
+// valid for, otherwise it stays a string.
+func infer(value string) interface{} {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return i
+	}
+
+	// ParseFloat accepts inf and nan, which are more likely meant as words
+	if strings.ContainsAny(value, "0123456789") {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+func (d *Decoder) newPointerSetter(t reflect.Type, f format) setter {
+	var (
+		elemType = t.Elem()
+		setElem  = d.newSetter(elemType, f)
+	)
+
+	return func(property reflect.Value, values []string) error {
+		ptr := reflect.New(elemType)
+		if err := setElem(ptr.Elem(), values); err != nil {
 			return err
 		}
-		property.Set(reflect.ValueOf(t))
-	default:
-		s := reflect.New(property.Type())
-		err := json.Unmarshal([]byte(values[0]), s.Interface())
-		if err != nil {
+		property.Set(ptr)
+		return nil
+	}
+}
+
+func newUnmarshalerSetter(t reflect.Type) setter {
+	return func(property reflect.Value, values []string) error {
+		v := reflect.New(t)
+		if err := v.Interface().(Unmarshaler).UnmarshalValues(values); err != nil {
 			return err
 		}
-		property.Set(s.Elem())
+		property.Set(v.Elem())
+		return nil
 	}
+}
+
+func newTextUnmarshalerSetter(t reflect.Type) setter {
+	return func(property reflect.Value, values []string) error {
+		v := reflect.New(t)
+		if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(values[0])); err != nil {
+			return err
+		}
+		property.Set(v.Elem())
+		return nil
+	}
+}
+
+func setStruct(property reflect.Value, values []string) error {
+	s := reflect.New(property.Type())
+	err := json.Unmarshal([]byte(values[0]), s.Interface())
+	if err != nil {
+		return err
+	}
+	property.Set(s.Elem())
 	return nil
 }
 
@@ -99,63 +241,214 @@ func setString(property reflect.Value, values []string) error {
 	return nil
 }
 
-func setSlice(property reflect.Value, values []string) error {
-	var (
-		propertyType        = property.Type()
-		propertyElementKind = propertyType.Elem().Kind()
-	)
+func (d *Decoder) newSliceSetter(t reflect.Type, f format) setter {
+	elemType := t.Elem()
 
-	switch propertyElementKind {
 	// case of a byte array
-	case reflect.Uint8:
-		values = strings.Split(values[0], "")
-		for i, c := range values {
-			values[i] = strconv.FormatUint(uint64([]byte(c)[0]), 10)
+	if elemType.Kind() == reflect.Uint8 {
+		return newBytesSetter(f.encoding)
+	}
+
+	setElem := d.newSetter(elemType, f)
+	return func(property reflect.Value, values []string) error {
+		var (
+			lenVals = len(values)
+			slice   = reflect.MakeSlice(t, lenVals, lenVals)
+		)
+
+		for i := 0; i < lenVals; i++ {
+			if err := setElem(slice.Index(i), values[i:i+1]); err != nil {
+				return err
+			}
 		}
+
+		property.Set(slice)
+		return nil
 	}
+}
+
+// pairSetter converts the given key/value pairs and assigns the result to
+// property.
+type pairSetter func(property reflect.Value, pairs map[string][]string) error
+
+// mapSetter fills a map either from key/value pairs or from values in the form
+// key=value.
+type mapSetter struct {
+	setPairs pairSetter
+}
 
+func (d *Decoder) newMapSetter(t reflect.Type, f format) mapSetter {
 	var (
-		lenVals = len(values)
-		slice   = reflect.MakeSlice(propertyType, lenVals, lenVals)
+		keyType  = t.Key()
+		elemType = t.Elem()
+		setKey   = d.newSetter(keyType, f)
+		setElem  = d.newSetter(elemType, f)
 	)
 
-	for i := 0; i < lenVals; i++ {
-		if err := setValue(slice.Index(i), values[i]); err != nil {
-			return err
+	return mapSetter{
+		setPairs: func(property reflect.Value, pairs map[string][]string) error {
+			m := reflect.MakeMapWithSize(t, len(pairs))
+			for _, k := range sortedKeys(pairs) {
+				var (
+					key  = reflect.New(keyType).Elem()
+					elem = reflect.New(elemType).Elem()
+				)
+
+				if err := setKey(key, []string{k}); err != nil {
+					return err
+				}
+				if err := setElem(elem, pairs[k]); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, elem)
+			}
+
+			property.Set(m)
+			return nil
+		},
+	}
+}
+
+func (s mapSetter) set(property reflect.Value, values []string) error {
+	pairs := make(map[string][]string, len(values))
+	for _, v := range values {
+		key, value, ok := strings.Cut(v, "=")
+		if !ok {
+			return fmt.Errorf("%q is not a key=value pair", v)
 		}
+		pairs[key] = append(pairs[key], value)
 	}
+	return s.setPairs(property, pairs)
+}
 
-	property.Set(slice)
-	return nil
+func sortedKeys(pairs map[string][]string) []string {
+	keys := make([]string, 0, len(pairs))
+	for k := range pairs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// byteEncodings are the encodings of byte values, which can be used in the
+// encoding tag. Without one the bytes of the value are taken as they are.
+var byteEncodings = map[string]func(value string) ([]byte, error){
+	"": func(value string) ([]byte, error) {
+		return []byte(value), nil
+	},
+	"base64": func(value string) ([]byte, error) {
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "="))
+	},
+	"base64url": func(value string) ([]byte, error) {
+		return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+	},
+	"hex": hex.DecodeString,
 }
 
-func setInt(property reflect.Value, values []string) error {
-	switch property.Interface().(type) {
-	case time.Duration:
-		d, err := time.ParseDuration(values[0])
+func newBytesSetter(encoding string) setter {
+	decode := byteEncodings[encoding]
+	return func(property reflect.Value, values []string) error {
+		b, err := decode(values[0])
 		if err != nil {
 			return err
 		}
-		property.SetInt(int64(d))
-	default:
-		v, err := strconv.ParseInt(values[0], 10, 64)
-		if err != nil {
+		property.SetBytes(b)
+		return nil
+	}
+}
+
+// newArraySetter fills an array with one element per value, like a slice. More
+// values than the array length fail, fewer ones only with StrictArrays.
+func (d *Decoder) newArraySetter(t reflect.Type, f format) setter {
+	var (
+		length   = t.Len()
+		elemType = t.Elem()
+		strict   = d.strictArrays
+	)
+
+	checkLength := func(n int) error {
+		if n > length || strict && n < length {
+			return fmt.Errorf("%d values don't fit into %s", n, t)
+		}
+		return nil
+	}
+
+	// case of a byte array, which is filled from a single value
+	if elemType.Kind() == reflect.Uint8 {
+		decode := byteEncodings[f.encoding]
+		return func(property reflect.Value, values []string) error {
+			b, err := decode(values[0])
+			if err != nil {
+				return err
+			}
+
+			if err := checkLength(len(b)); err != nil {
+				return err
+			}
+
+			array := reflect.New(t).Elem()
+			for i, c := range b {
+				array.Index(i).SetUint(uint64(c))
+			}
+
+			property.Set(array)
+			return nil
+		}
+	}
+
+	setElem := d.newSetter(elemType, f)
+	return func(property reflect.Value, values []string) error {
+		if err := checkLength(len(values)); err != nil {
 			return err
 		}
-		property.SetInt(v)
+
+		array := reflect.New(t).Elem()
+		for i := range values {
+			if err := setElem(array.Index(i), values[i:i+1]); err != nil {
+				return err
+			}
+		}
+
+		property.Set(array)
+		return nil
 	}
-	return nil
 }
 
-func setUInt(property reflect.Value, values []string) error {
-	ui, err := strconv.ParseUint(values[0], 10, 64)
+func setDuration(property reflect.Value, values []string) error {
+	d, err := time.ParseDuration(values[0])
 	if err != nil {
 		return err
 	}
-	property.SetUint(ui)
+	property.SetInt(int64(d))
 	return nil
 }
 
+// newIntSetter parses values with the size of the property, so values out of
+// its range fail with strconv.ErrRange instead of overflowing.
+func newIntSetter(bitSize int) setter {
+	return func(property reflect.Value, values []string) error {
+		v, err := strconv.ParseInt(values[0], 10, bitSize)
+		if err != nil {
+			return err
+		}
+		property.SetInt(v)
+		return nil
+	}
+}
+
+// newUIntSetter parses values with the size of the property, so values out of
+// its range fail with strconv.ErrRange instead of overflowing.
+func newUIntSetter(bitSize int) setter {
+	return func(property reflect.Value, values []string) error {
+		ui, err := strconv.ParseUint(values[0], 10, bitSize)
+		if err != nil {
+			return err
+		}
+		property.SetUint(ui)
+		return nil
+	}
+}
+
 func setBool(property reflect.Value, values []string) error {
 	b, err := strconv.ParseBool(values[0])
 	if err != nil {
@@ -197,77 +490,184 @@ func Values(v []string) Valuer {
 	return values(v)
 }
 
+// KeyValues converts key/value pairs to a Valuer interface, e.g. to fill a map
+// field. Fields of other types receive the pairs in the form key=value.
+func KeyValues(kv map[string][]string) Valuer {
+	return keyValues(kv)
+}
+
+// Split converts a list of values joined by sep to a Valuer interface. It is
+// split into its elements for slice fields only, any other field receives v as
+// it is. Elements in double quotes may contain sep like with the split tag,
+// which replaces sep for the field. An empty v doesn't contain any element.
+func Split(v, sep string) Valuer {
+	return split{value: v, sep: sep}
+}
+
 type values []string
 
 func (v values) values() []string {
 	return v
 }
 
-// Source defines the source of a given struct field tag.
-//
-// Tag contains the field tag name
-// Get is a function to get the value/values for your given field.
-type Source struct {
-	Tag string
-	Get func(string) (Valuer, error)
+type keyValues map[string][]string
+
+func (kv keyValues) values() []string {
+	var v []string
+	for _, key := range sortedKeys(kv) {
+		for _, value := range kv[key] {
+			v = append(v, key+"="+value)
+		}
+	}
+	return v
 }
 
-type Sources []Source
+type split struct {
+	value string
+	sep   string
+}
 
-func From(sources []Source) Sources {
-	return sources
+func (s split) values() []string {
+	return []string{s.value}
 }
 
-// To takes the given sources and try to fill the fields of the given struct.
-func (sources Sources) To(obj interface{}) error {
-	if obj == nil {
-		return errors.New("given struct to fill is nil")
-	}
+// splitValues splits every value at sep into its items. Items in double quotes
+// may contain sep, a double quote within them is escaped by another one, e.g.
+// "a,""b""",c results in the items a,"b" and c. Empty values don't contain
+// any item.
+func splitValues(values []string, sep string) ([]string, error) {
+	var items []string
+	for _, v := range values {
+		if v == "" {
+			continue
+		}
 
-	if len(sources) == 0 {
-		return nil
-	}
+		var (
+			item   strings.Builder
+			quoted bool
+			start  = true
+		)
+		for i := 0; i < len(v); {
+			switch {
+			case quoted && strings.HasPrefix(v[i:], `""`):
+				item.WriteByte('"')
+				i += 2
+			case quoted && v[i] == '"':
+				quoted = false
+				i++
+			case quoted:
+				item.WriteByte(v[i])
+				i++
+			case start && v[i] == '"':
+				quoted = true
+				start = false
+				i++
+			case strings.HasPrefix(v[i:], sep):
+				items = append(items, item.String())
+				item.Reset()
+				start = true
+				i += len(sep)
+			default:
+				item.WriteByte(v[i])
+				start = false
+				i++
+			}
+		}
 
-	valueOf := reflect.ValueOf(obj)
-	for valueOf.Kind() == reflect.Ptr {
-		valueOf = valueOf.Elem()
+		if quoted {
+			return nil, fmt.Errorf("missing closing quote in %q", v)
+		}
+		items = append(items, item.String())
 	}
+	return items, nil
+}
 
-	t := valueOf.Type()
-	for i := 0; i < valueOf.NumField(); i++ {
-		for _, source := range sources {
-			field := t.Field(i)
-
-			tagValue, ok := field.Tag.Lookup(source.Tag)
-			if !ok {
-				continue
-			}
-
-			property := valueOf.Field(i)
-			if !property.IsValid() || !property.CanSet() {
-				continue
-			}
+// Source defines the source of a given struct field tag.
+//
+// Tag contains the field tag name
+// Get is a function to get the value/values for your given field.
+// GetContext is used instead of Get if set. It receives the context given to
+// ToContext, e.g. to cancel requests of a remote source.
+// GetMany is used instead of both if set. It is called once per decoding with
+// the names of all fields and returns their values, fields missing in the map
+// have no value. If it fails, every field of the source fails with its error.
+// GetManyContext is used instead of GetMany if set and receives the context
+// like GetContext.
+// Prefix is optional and put in front of the names of all fields, so it is
+// part of the field name of an Error.
+// Nest is optional and returns the prefix of the names of the fields in the
+// nested struct field name. It gets the prefix of the current struct, which is
+// Prefix at the top level. Without Nest the names of nested fields are used
+// with the prefix of the current struct.
+type Source struct {
+	Tag            string
+	Get            func(string) (Valuer, error)
+	GetContext     func(ctx context.Context, field string) (Valuer, error)
+	GetMany        func(fields []string) (map[string]Valuer, error)
+	GetManyContext func(ctx context.Context, fields []string) (map[string]Valuer, error)
+	Prefix         string
+	Nest           func(prefix, name string) string
+}
 
-			var values []string
-			v, err := source.Get(tagValue)
+// get returns the value of the field. A source without a getter provides no
+// values.
+func (s Source) get(ctx context.Context, field string) (Valuer, error) {
+	switch {
+	case s.GetContext != nil:
+		return s.GetContext(ctx, field)
+	case s.Get != nil:
+		return s.Get(field)
+	}
+	return nil, nil
+}
 
-			if v != nil {
-				values = v.values()
-			}
+// batch reports whether the source gets the values of all fields at once.
+func (s Source) batch() bool {
+	return s.GetManyContext != nil || s.GetMany != nil
+}
 
-			if err != nil {
-				return newError(tagValue, source.Tag, values, err)
-			}
+// getMany returns the values of the fields of a batch source.
+func (s Source) getMany(ctx context.Context, fields []string) (map[string]Valuer, error) {
+	if s.GetManyContext != nil {
+		return s.GetManyContext(ctx, fields)
+	}
+	return s.GetMany(fields)
+}
 
-			if len(values) == 0 {
-				continue
-			}
+type Sources []Source
 
-			err = setValue(property, values...)
-			if err != nil {
-				return newError(tagValue, source.Tag, values, err)
+// prefixes returns the Prefix of every source or nil if none has one.
+func (sources Sources) prefixes() []string {
+	for _, source := range sources {
+		if source.Prefix != "" {
+			prefixes := make([]string, len(sources))
+			for i, source := range sources {
+				prefixes[i] = source.Prefix
 			}
+			return prefixes
 		}
 	}
 	return nil
 }
+
+func From(sources []Source) Sources {
+	return sources
+}
+
+// To takes the given sources and try to fill the fields of the given struct.
+func (sources Sources) To(obj interface{}) error {
+	return defaultDecoder.Decode(sources, obj)
+}
+
+// ToContext is like To but passes ctx to the sources, see
+// Decoder.DecodeContext.
+func (sources Sources) ToContext(ctx context.Context, obj interface{}) error {
+	return defaultDecoder.DecodeContext(ctx, sources, obj)
+}
+
+// Bind returns a T filled from the given sources, see BindWith.
+//
+//	req, err := handgover.Bind[CreateOrderRequest](sources)
+func Bind[T any](sources []Source) (T, error) {
+	return BindWith[T](defaultDecoder, sources)
+}
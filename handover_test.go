@@ -22,6 +22,11 @@ package handgover
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -1003,3 +1008,1696 @@ func TestFillIfSourceReturnsAnError(t *testing.T) {
 
 	assert.Equal(t, "hello world", s.String)
 }
+
+type Pagination struct {
+	Limit  int `foo:"limit"`
+	Offset int `foo:"offset"`
+}
+
+type pagination struct {
+	Page int `foo:"page"`
+}
+
+func paginationSources(t *testing.T) []Source {
+	return []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "limit":
+					return Value("20"), nil
+				case "offset":
+					return Value("40"), nil
+				case "page":
+					return Value("3"), nil
+				case "q":
+					return Value("test"), nil
+				}
+				t.Errorf("unexpected field %q", field)
+				return nil, nil
+			},
+		},
+	}
+}
+
+func TestFillEmbeddedStruct(t *testing.T) {
+
+	var s struct {
+		Pagination
+		pagination
+		Query string `foo:"q"`
+	}
+
+	assert.NoError(t, From(paginationSources(t)).To(&s))
+	assert.Equal(t, 20, s.Limit)
+	assert.Equal(t, 40, s.Offset)
+	assert.Equal(t, 3, s.Page)
+	assert.Equal(t, "test", s.Query)
+}
+
+func TestFillNestedStruct(t *testing.T) {
+
+	var s struct {
+		Pagination Pagination `json:"pagination"`
+		Nested     struct {
+			Query string `foo:"q"`
+		}
+	}
+
+	assert.NoError(t, From(paginationSources(t)).To(&s))
+	assert.Equal(t, 20, s.Pagination.Limit)
+	assert.Equal(t, 40, s.Pagination.Offset)
+	assert.Equal(t, "test", s.Nested.Query)
+}
+
+func TestFillNestedStructPointer(t *testing.T) {
+
+	var s struct {
+		*Pagination
+		Existing *Pagination
+		Empty    *struct {
+			Unknown string `john:"doe"`
+		}
+	}
+	s.Existing = &Pagination{Limit: 1, Offset: 2}
+	existing := s.Existing
+
+	assert.NoError(t, From(paginationSources(t)).To(&s))
+
+	assert.NotNil(t, s.Pagination)
+	assert.Equal(t, 20, s.Pagination.Limit)
+	assert.Equal(t, 40, s.Pagination.Offset)
+
+	assert.True(t, existing == s.Existing)
+	assert.Equal(t, 20, s.Existing.Limit)
+
+	assert.Nil(t, s.Empty)
+}
+
+func TestFillNestedStructWithInvalidValue(t *testing.T) {
+
+	var s struct {
+		Pagination *Pagination
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("invalid"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.Error(t, err)
+
+	var parsedErr Error
+
+	assert.True(t, errors.As(err, &parsedErr))
+	assert.Equal(t, "limit", parsedErr.Field)
+	assert.Equal(t, "invalid", parsedErr.Value)
+
+	assert.Nil(t, s.Pagination)
+}
+
+func TestFillRecursiveStruct(t *testing.T) {
+
+	type node struct {
+		Value string `foo:"bar"`
+		Next  *node
+	}
+
+	var s node
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				assert.Equal(t, "bar", field)
+				return Value("helloworld"), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, "helloworld", s.Value)
+	assert.Nil(t, s.Next)
+}
+
+func TestFillDefault(t *testing.T) {
+
+	var s struct {
+		Limit   int     `foo:"limit" default:"20"`
+		Offset  int     `foo:"offset" default:"0"`
+		Pointer *string `foo:"pointer" default:"helloworld"`
+		Sort    string  `default:"name"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "offset" {
+					return Value("40"), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, 20, s.Limit)
+	assert.Equal(t, 40, s.Offset)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, "helloworld", *s.Pointer)
+	assert.Equal(t, "name", s.Sort)
+}
+
+func TestFillDefaultWithoutSources(t *testing.T) {
+
+	type request struct {
+		Limit    int    `foo:"limit" default:"20"`
+		Sort     string `default:"name"`
+		Required string `foo:"id" required:"true"`
+		Nested   struct {
+			Page int `foo:"page" default:"1"`
+		}
+	}
+
+	var s request
+	assert.NoError(t, From(nil).To(&s))
+	assert.Equal(t, 20, s.Limit)
+	assert.Equal(t, "name", s.Sort)
+	assert.Equal(t, 1, s.Nested.Page)
+
+	bound, err := Bind[request](nil)
+	assert.NoError(t, err)
+	assert.Equal(t, s, bound)
+}
+
+func TestFillDefaultNotUsedOnInvalidValue(t *testing.T) {
+
+	var s struct {
+		Limit int `foo:"limit" default:"20"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("invalid"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrConversion))
+	assert.Equal(t, 0, s.Limit)
+}
+
+func TestFillWithInvalidDefault(t *testing.T) {
+
+	var s struct {
+		Limit int `foo:"limit" default:"twenty"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				t.Error("source must not be called")
+				return Value("1"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.Error(t, err)
+
+	var parsedErr Error
+
+	assert.True(t, errors.As(err, &parsedErr))
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+	assert.Equal(t, "Limit", parsedErr.Field)
+	assert.Equal(t, "default", parsedErr.Source)
+	assert.Equal(t, "twenty", parsedErr.Value)
+	assert.Equal(t, 0, s.Limit)
+}
+
+func TestFillRequired(t *testing.T) {
+
+	var s struct {
+		ID     string `foo:"id" john:"id" required:"true"`
+		Limit  int    `foo:"limit" required:"true" default:"20"`
+		Offset int    `foo:"offset" required:"false"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return nil, nil
+			},
+		},
+		{
+			Tag: "john",
+			Get: func(field string) (Valuer, error) {
+				return Value("helloworld"), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, "helloworld", s.ID)
+	assert.Equal(t, 20, s.Limit)
+}
+
+func TestFillRequiredWithMissingValue(t *testing.T) {
+
+	var s struct {
+		ID     string `foo:"id" john:"identifier" required:"true"`
+		Offset int    `foo:"offset"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "john",
+			Get: func(field string) (Valuer, error) {
+				return nil, nil
+			},
+		},
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "offset" {
+					return Value("invalid"), nil
+				}
+				return Values(nil), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+	assert.Error(t, err)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+
+	assert.Equal(t, "identifier", errs[0].Field)
+	assert.Equal(t, "john", errs[0].Source)
+	assert.Equal(t, "", errs[0].Value)
+	assert.Equal(t, ErrMissingValue, errs[0].InnerError)
+	assert.True(t, errors.Is(errs[0], ErrMissingValue))
+	assert.False(t, errors.Is(errs[0], ErrConversion))
+
+	assert.False(t, errors.Is(errs[1], ErrMissingValue))
+	assert.True(t, errors.Is(errs[1], ErrConversion))
+}
+
+func TestFillRequiredInOptionalNestedStruct(t *testing.T) {
+
+	type group struct {
+		Name  string `foo:"name" required:"true"`
+		Label string `foo:"label"`
+	}
+
+	var s struct {
+		Optional *group
+		Offset   int `foo:"offset"`
+	}
+
+	values := map[string]Valuer{"offset": Value("10")}
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return values[field], nil
+			},
+			Nest: func(prefix, name string) string {
+				return prefix + strings.ToLower(name) + "."
+			},
+		},
+	}
+
+	// a struct none of the sources provides isn't missing its fields
+	assert.NoError(t, From(sources).To(&s))
+	assert.Nil(t, s.Optional)
+	assert.Equal(t, 10, s.Offset)
+
+	// once it is provided, its required fields are
+	values["optional.label"] = Value("abc")
+
+	err := From(sources).To(&s)
+	assert.True(t, errors.Is(err, ErrMissingValue))
+
+	hErr, ok := FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "optional.name", hErr.Field)
+	assert.NotNil(t, s.Optional)
+	assert.Equal(t, "abc", s.Optional.Label)
+}
+
+func TestFillRequiredWithInvalidTag(t *testing.T) {
+
+	var s struct {
+		ID string `foo:"id" required:"yes please"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("helloworld"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	var parsedErr Error
+
+	assert.True(t, errors.As(err, &parsedErr))
+	assert.Equal(t, "ID", parsedErr.Field)
+	assert.Equal(t, "required", parsedErr.Source)
+	assert.Equal(t, "yes please", parsedErr.Value)
+}
+
+func TestFillSplit(t *testing.T) {
+
+	var s struct {
+		Slice   []int    `foo:"bar"`
+		Pointer *[]int   `foo:"bar"`
+		String  string   `foo:"bar"`
+		Empty   []string `foo:"empty"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "empty" {
+					return Split("", ","), nil
+				}
+				return Split("1,2,3", ","), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, []int{1, 2, 3}, s.Slice)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, []int{1, 2, 3}, *s.Pointer)
+	assert.Equal(t, "1,2,3", s.String)
+	assert.Nil(t, s.Empty)
+}
+
+func TestDecodeSplitValuerSeparator(t *testing.T) {
+
+	var s struct {
+		Quoted  []string `foo:"quoted"`
+		Tagged  []string `foo:"list" split:";"`
+		Decoder []string `foo:"list"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "quoted" {
+					return Split(`"a,b",c`, ","), nil
+				}
+				return Split("a;b,c", ","), nil
+			},
+		},
+	}
+
+	// the values are split once, at the split tag or else at the separator of
+	// the valuer instead of the one of the decoder
+	assert.NoError(t, NewDecoder(SplitSeparator(";")).Decode(sources, &s))
+	assert.Equal(t, []string{"a,b", "c"}, s.Quoted)
+	assert.Equal(t, []string{"a", "b,c"}, s.Tagged)
+	assert.Equal(t, []string{"a;b", "c"}, s.Decoder)
+}
+
+func TestFillNestedStructWithNest(t *testing.T) {
+
+	var s struct {
+		Pagination
+		Nested struct {
+			Pagination Pagination
+		}
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "limit":
+					return Value("1"), nil
+				case "nested.pagination.limit":
+					return Value("2"), nil
+				}
+				return nil, nil
+			},
+			Nest: func(prefix, name string) string {
+				return prefix + strings.ToLower(name) + "."
+			},
+		},
+		{
+			Tag: "john",
+			Get: func(field string) (Valuer, error) {
+				t.Errorf("unexpected field %q", field)
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, 1, s.Limit)
+	assert.Equal(t, 2, s.Nested.Pagination.Limit)
+}
+
+func TestFillWithPrefix(t *testing.T) {
+
+	var s struct {
+		Pagination
+		Nested struct {
+			Pagination Pagination
+		}
+		Count int `foo:"count" john:"count"`
+	}
+
+	sources := []Source{
+		{
+			Tag:    "foo",
+			Prefix: "app.",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "app.limit":
+					return Value("1"), nil
+				case "app.nested.pagination.limit":
+					return Value("2"), nil
+				case "app.count":
+					return Value("abc"), nil
+				}
+				return nil, nil
+			},
+			Nest: func(prefix, name string) string {
+				return prefix + strings.ToLower(name) + "."
+			},
+		},
+		{
+			Tag: "john",
+			Get: func(field string) (Valuer, error) {
+				assert.Equal(t, "count", field)
+				return nil, nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.Equal(t, 1, s.Limit)
+	assert.Equal(t, 2, s.Nested.Pagination.Limit)
+
+	// errors name the field with its prefix
+	hErr, ok := FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "app.count", hErr.Field)
+}
+
+func TestFillMap(t *testing.T) {
+
+	var s struct {
+		Labels map[string]string   `foo:"labels"`
+		Counts map[string]int      `foo:"counts"`
+		Multi  map[int][]string    `foo:"multi"`
+		Flat   map[string]string   `foo:"flat"`
+		Values map[string][]string `john:"values"`
+	}
+	s.Labels = map[string]string{"old": "value"}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "labels":
+					return KeyValues(map[string][]string{"env": {"prod"}, "team": {"orders"}}), nil
+				case "counts":
+					return KeyValues(map[string][]string{"a": {"1"}, "b": {"2"}}), nil
+				case "multi":
+					return KeyValues(map[string][]string{"1": {"a", "b"}}), nil
+				case "flat":
+					return Values([]string{"env=prod", "query=a=b"}), nil
+				}
+				return nil, nil
+			},
+		},
+		{
+			Tag: "john",
+			Get: func(field string) (Valuer, error) {
+				return Split("a=1,a=2,b=3", ","), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, map[string]string{"env": "prod", "team": "orders"}, s.Labels)
+	assert.Equal(t, map[string]int{"a": 1, "b": 2}, s.Counts)
+	assert.Equal(t, map[int][]string{1: {"a", "b"}}, s.Multi)
+	assert.Equal(t, map[string]string{"env": "prod", "query": "a=b"}, s.Flat)
+	assert.Equal(t, map[string][]string{"a": {"1", "2"}, "b": {"3"}}, s.Values)
+}
+
+func TestFillMapWithInvalidValue(t *testing.T) {
+
+	var s struct {
+		Counts map[string]int  `foo:"counts"`
+		Keys   map[int]string  `foo:"keys"`
+		Flat   map[string]bool `foo:"flat"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "counts":
+					return KeyValues(map[string][]string{"a": {"1"}, "b": {"invalid"}}), nil
+				case "keys":
+					return KeyValues(map[string][]string{"invalid": {"a"}}), nil
+				}
+				return Value("novalue"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+
+	assert.Equal(t, "counts", errs[0].Field)
+	assert.Equal(t, "invalid", errs[0].Value)
+	assert.Equal(t, "keys", errs[1].Field)
+	assert.Equal(t, "invalid", errs[1].Value)
+	assert.Equal(t, "flat", errs[2].Field)
+	assert.Equal(t, "novalue", errs[2].Value)
+
+	assert.Nil(t, s.Counts)
+	assert.Nil(t, s.Keys)
+	assert.Nil(t, s.Flat)
+}
+
+type orderID string
+
+func (id *orderID) UnmarshalValues(values []string) error {
+	if !strings.HasPrefix(values[0], "order-") {
+		return fmt.Errorf("invalid order id %q", values[0])
+	}
+	*id = orderID(strings.TrimPrefix(values[0], "order-"))
+	return nil
+}
+
+type orderIDs []string
+
+func (ids *orderIDs) UnmarshalValues(values []string) error {
+	*ids = append(orderIDs{}, values...)
+	return nil
+}
+
+type status int
+
+func (s *status) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "open":
+		*s = 1
+	case "closed":
+		*s = 2
+	default:
+		return fmt.Errorf("unknown status %q", text)
+	}
+	return nil
+}
+
+type money struct {
+	Amount   int    `foo:"amount"`
+	Currency string `foo:"currency"`
+}
+
+func (m *money) UnmarshalText(text []byte) error {
+	_, err := fmt.Sscanf(string(text), "%d %s", &m.Amount, &m.Currency)
+	return err
+}
+
+func TestFillUnmarshaler(t *testing.T) {
+
+	var s struct {
+		ID      orderID        `foo:"id"`
+		Pointer *orderID       `foo:"id"`
+		IDs     orderIDs       `foo:"ids"`
+		Status  status         `foo:"status"`
+		Money   money          `foo:"money"`
+		Nested  money          `json:"nested"`
+		IP      net.IP         `foo:"ip"`
+		IPs     []net.IP       `foo:"ips"`
+		Addr    netip.Addr     `foo:"ip"`
+		Ptr     *netip.Addr    `foo:"ip"`
+		Keys    map[status]int `foo:"keys"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "id":
+					return Value("order-42"), nil
+				case "ids":
+					return Values([]string{"a", "b"}), nil
+				case "status":
+					return Value("closed"), nil
+				case "money", "amount":
+					return Value("10 EUR"), nil
+				case "ip":
+					return Value("10.0.0.1"), nil
+				case "ips":
+					return Values([]string{"10.0.0.1", "::1"}), nil
+				case "keys":
+					return Value("open=1"), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, orderID("42"), s.ID)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, orderID("42"), *s.Pointer)
+	assert.Equal(t, orderIDs{"a", "b"}, s.IDs)
+	assert.Equal(t, status(2), s.Status)
+	assert.Equal(t, money{Amount: 10, Currency: "EUR"}, s.Money)
+	assert.Equal(t, money{}, s.Nested)
+	assert.Equal(t, net.ParseIP("10.0.0.1"), s.IP)
+	assert.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")}, s.IPs)
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), s.Addr)
+	assert.NotNil(t, s.Ptr)
+	assert.Equal(t, netip.MustParseAddr("10.0.0.1"), *s.Ptr)
+	assert.Equal(t, map[status]int{1: 1}, s.Keys)
+}
+
+func TestFillUnmarshalerWithInvalidValue(t *testing.T) {
+
+	var s struct {
+		ID     orderID    `foo:"id"`
+		Status status     `foo:"status"`
+		Addr   netip.Addr `foo:"addr"`
+	}
+	s.ID = "1"
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("invalid"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+
+	for _, e := range errs {
+		assert.Equal(t, "invalid", e.Value)
+		assert.True(t, errors.Is(e, ErrConversion))
+	}
+	assert.Equal(t, `invalid order id "invalid"`, errs[0].InnerError.Error())
+
+	assert.Equal(t, orderID("1"), s.ID)
+}
+
+func TestFillInt8Limits(t *testing.T) {
+
+	for _, value := range []string{"127", "-128"} {
+		var s struct {
+			Int8 int8 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatInt(int64(s.Int8), 10))
+	}
+}
+
+func TestFillInt8WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"128", "-129"} {
+		var s struct {
+			Int8 int8 `foo:"bar"`
+		}
+		s.Int8 = int8(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, int8(1), s.Int8)
+	}
+}
+
+func TestFillInt16Limits(t *testing.T) {
+
+	for _, value := range []string{"32767", "-32768"} {
+		var s struct {
+			Int16 int16 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatInt(int64(s.Int16), 10))
+	}
+}
+
+func TestFillInt16WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"32768", "-32769"} {
+		var s struct {
+			Int16 int16 `foo:"bar"`
+		}
+		s.Int16 = int16(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, int16(1), s.Int16)
+	}
+}
+
+func TestFillInt32Limits(t *testing.T) {
+
+	for _, value := range []string{"2147483647", "-2147483648"} {
+		var s struct {
+			Int32 int32 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatInt(int64(s.Int32), 10))
+	}
+}
+
+func TestFillInt32WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"2147483648", "-2147483649"} {
+		var s struct {
+			Int32 int32 `foo:"bar"`
+		}
+		s.Int32 = int32(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, int32(1), s.Int32)
+	}
+}
+
+func TestFillInt64Limits(t *testing.T) {
+
+	for _, value := range []string{"9223372036854775807", "-9223372036854775808"} {
+		var s struct {
+			Int64 int64 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatInt(s.Int64, 10))
+	}
+}
+
+func TestFillInt64WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"9223372036854775808", "-9223372036854775809"} {
+		var s struct {
+			Int64 int64 `foo:"bar"`
+		}
+		s.Int64 = int64(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, int64(1), s.Int64)
+	}
+}
+
+func TestFillUInt8Limits(t *testing.T) {
+
+	for _, value := range []string{"255", "0"} {
+		var s struct {
+			UInt8 uint8 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatUint(uint64(s.UInt8), 10))
+	}
+}
+
+func TestFillUInt8WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"256"} {
+		var s struct {
+			UInt8 uint8 `foo:"bar"`
+		}
+		s.UInt8 = uint8(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, uint8(1), s.UInt8)
+	}
+}
+
+func TestFillUInt16Limits(t *testing.T) {
+
+	for _, value := range []string{"65535", "0"} {
+		var s struct {
+			UInt16 uint16 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatUint(uint64(s.UInt16), 10))
+	}
+}
+
+func TestFillUInt16WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"65536"} {
+		var s struct {
+			UInt16 uint16 `foo:"bar"`
+		}
+		s.UInt16 = uint16(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, uint16(1), s.UInt16)
+	}
+}
+
+func TestFillUInt32Limits(t *testing.T) {
+
+	for _, value := range []string{"4294967295", "0"} {
+		var s struct {
+			UInt32 uint32 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatUint(uint64(s.UInt32), 10))
+	}
+}
+
+func TestFillUInt32WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"4294967296"} {
+		var s struct {
+			UInt32 uint32 `foo:"bar"`
+		}
+		s.UInt32 = uint32(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, uint32(1), s.UInt32)
+	}
+}
+
+func TestFillUInt64Limits(t *testing.T) {
+
+	for _, value := range []string{"18446744073709551615", "0"} {
+		var s struct {
+			UInt64 uint64 `foo:"bar"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		assert.NoError(t, From(sources).To(&s))
+		assert.Equal(t, value, strconv.FormatUint(s.UInt64, 10))
+	}
+}
+
+func TestFillUInt64WithOverflow(t *testing.T) {
+
+	for _, value := range []string{"18446744073709551616"} {
+		var s struct {
+			UInt64 uint64 `foo:"bar"`
+		}
+		s.UInt64 = uint64(1)
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					assert.Equal(t, "bar", field)
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err)
+
+		var parsedErr Error
+
+		assert.True(t, errors.As(err, &parsedErr))
+		assert.Equal(t, "bar", parsedErr.Field)
+		assert.Equal(t, "foo", parsedErr.Source)
+		assert.Equal(t, value, parsedErr.Value)
+		assert.True(t, errors.Is(err, strconv.ErrRange))
+
+		assert.Equal(t, uint64(1), s.UInt64)
+	}
+}
+
+func TestFillIntWithOverflow(t *testing.T) {
+
+	var s struct {
+		Int   int           `foo:"int"`
+		UInt  uint          `foo:"int"`
+		Slice []int8        `foo:"slice"`
+		Map   map[int8]bool `foo:"map"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "slice":
+					return Values([]string{"1", "300"}), nil
+				case "map":
+					return Value("300=true"), nil
+				}
+				return Value("18446744073709551616"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 4)
+	assert.Equal(t, "18446744073709551616", errs[0].Value)
+	assert.Equal(t, "18446744073709551616", errs[1].Value)
+	assert.Equal(t, "300", errs[2].Value)
+	assert.Equal(t, "300", errs[3].Value)
+	for _, e := range errs {
+		assert.True(t, errors.Is(e, strconv.ErrRange))
+	}
+}
+
+func TestFillSplitTag(t *testing.T) {
+
+	var s struct {
+		IDs      []int             `foo:"ids" split:","`
+		Pointer  *[]int            `foo:"ids" split:","`
+		Quoted   []string          `foo:"quoted" split:","`
+		Pipe     []string          `foo:"pipe" split:"||"`
+		Labels   map[string]string `foo:"labels" split:";"`
+		Defaults []int             `foo:"missing" split:"," default:"4,5"`
+		Empty    []int             `foo:"empty" split:","`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "ids":
+					return Values([]string{"1,2", "3"}), nil
+				case "quoted":
+					return Value(`"a,b",c,"say ""hi""",d"e`), nil
+				case "pipe":
+					return Value("a||b|c"), nil
+				case "labels":
+					return Value("env=prod;team=orders"), nil
+				case "empty":
+					return Values([]string{"", ""}), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, []int{1, 2, 3}, s.IDs)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, []int{1, 2, 3}, *s.Pointer)
+	assert.Equal(t, []string{"a,b", "c", `say "hi"`, `d"e`}, s.Quoted)
+	assert.Equal(t, []string{"a", "b|c"}, s.Pipe)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "orders"}, s.Labels)
+	assert.Equal(t, []int{4, 5}, s.Defaults)
+	assert.Nil(t, s.Empty)
+}
+
+func TestDecodeSplitSeparator(t *testing.T) {
+
+	var s struct {
+		IDs    []int    `foo:"ids"`
+		Pipe   []string `foo:"ids" split:"|"`
+		NoTag  []string `foo:"ids" split:""`
+		String string   `foo:"ids"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Values([]string{"1,2", "3"}), nil
+			},
+		},
+	}
+
+	assert.NoError(t, NewDecoder(SplitSeparator(",")).Decode(sources, &s))
+	assert.Equal(t, []int{1, 2, 3}, s.IDs)
+	assert.Equal(t, []string{"1,2", "3"}, s.Pipe)
+	assert.Equal(t, []string{"1,2", "3"}, s.NoTag)
+	assert.Equal(t, "1,2", s.String)
+}
+
+func TestDecodeSplitKeyValues(t *testing.T) {
+
+	var s struct {
+		Labels map[string][]int  `foo:"labels"`
+		Tagged map[string][]int  `foo:"tagged" split:";"`
+		Pairs  map[string]string `foo:"pairs"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "labels":
+					return KeyValues(map[string][]string{"a": {"1,2", "3"}, "b": {"4"}}), nil
+				case "tagged":
+					return KeyValues(map[string][]string{"a": {"1;2"}}), nil
+				}
+				return Value("a=1,b=2"), nil
+			},
+		},
+	}
+
+	// the values of key/value pairs are split, values in the form key=value
+	// into pairs
+	assert.NoError(t, NewDecoder(SplitSeparator(",")).Decode(sources, &s))
+	assert.Equal(t, map[string][]int{"a": {1, 2, 3}, "b": {4}}, s.Labels)
+	assert.Equal(t, map[string][]int{"a": {1, 2}}, s.Tagged)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, s.Pairs)
+}
+
+func TestFillSplitWithInvalidValue(t *testing.T) {
+
+	var s struct {
+		Quoted []string `foo:"quoted" split:","`
+		IDs    []int    `foo:"ids" split:","`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "quoted" {
+					return Value(`a,"b`), nil
+				}
+				return Value("1,x"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, `a,"b`, errs[0].Value)
+	assert.True(t, errors.Is(errs[0], ErrConversion))
+	assert.Equal(t, "x", errs[1].Value)
+	assert.Nil(t, s.Quoted)
+	assert.Nil(t, s.IDs)
+}
+
+func TestFillSplitWithInvalidTag(t *testing.T) {
+
+	var s struct {
+		Int int `foo:"bar" split:","`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("1"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.True(t, errors.Is(err, ErrInvalidTag))
+
+	hErr, ok := FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "Int", hErr.Field)
+	assert.Equal(t, "split", hErr.Source)
+}
+
+func TestFillArray(t *testing.T) {
+
+	var s struct {
+		Coordinates [3]float64  `foo:"coordinates"`
+		Pointer     *[3]float64 `foo:"coordinates"`
+		Short       [4]int      `foo:"short"`
+		Split       [3]int      `foo:"split" split:","`
+		Bytes       [4]byte     `foo:"bytes"`
+		ShortBytes  [4]byte     `foo:"short"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "coordinates":
+					return Values([]string{"52.52", "13.405", "34"}), nil
+				case "short":
+					return Values([]string{"1", "2"}), nil
+				case "split":
+					return Value("1,2,3"), nil
+				case "bytes":
+					return Value("abcd"), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, [3]float64{52.52, 13.405, 34}, s.Coordinates)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, [3]float64{52.52, 13.405, 34}, *s.Pointer)
+	assert.Equal(t, [4]int{1, 2, 0, 0}, s.Short)
+	assert.Equal(t, [3]int{1, 2, 3}, s.Split)
+	assert.Equal(t, [4]byte{'a', 'b', 'c', 'd'}, s.Bytes)
+	assert.Equal(t, [4]byte{'1', 0, 0, 0}, s.ShortBytes)
+}
+
+func TestFillArrayWithInvalidLength(t *testing.T) {
+
+	var s struct {
+		Long      [2]int  `foo:"long"`
+		Short     [4]int  `foo:"short"`
+		LongBytes [2]byte `foo:"bytes"`
+		Invalid   [2]int  `foo:"invalid"`
+	}
+	s.Long = [2]int{1, 1}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "bytes":
+					return Value("abc"), nil
+				case "invalid":
+					return Values([]string{"1", "x"}), nil
+				}
+				return Values([]string{"1", "2", "3"}), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors(), StrictArrays()).Decode(sources, &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 4)
+	assert.Equal(t, "long", errs[0].Field)
+	assert.Equal(t, "[1 2 3]", errs[0].Value)
+	assert.Equal(t, `failed to set field "long" from source "foo": 3 values don't fit into [2]int`, errs[0].Error())
+	assert.Equal(t, "short", errs[1].Field)
+	assert.Equal(t, "bytes", errs[2].Field)
+	assert.Equal(t, "x", errs[3].Value)
+
+	assert.Equal(t, [2]int{1, 1}, s.Long)
+	assert.Equal(t, [2]int{}, s.Invalid)
+}
+
+func TestFillBytesEncoding(t *testing.T) {
+
+	var s struct {
+		Raw       []byte           `foo:"raw"`
+		RawArray  [6]byte          `foo:"raw"`
+		Base64    []byte           `foo:"base64" encoding:"base64"`
+		Unpadded  []byte           `foo:"unpadded" encoding:"base64"`
+		Base64URL []byte           `foo:"base64url" encoding:"base64url"`
+		Hex       []byte           `foo:"hex" encoding:"hex"`
+		HexArray  [4]byte          `foo:"hex" encoding:"hex"`
+		Pointer   *[]byte          `foo:"hex" encoding:"hex"`
+		RawJSON   *json.RawMessage `foo:"raw"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value(map[string]string{
+					"raw":       "héllo",
+					"base64":    "aGVsbG8/Pz8=",
+					"unpadded":  "aGVsbG8/Pz8",
+					"base64url": "aGVsbG8_Pz8",
+					"hex":       "deadbeef",
+				}[field]), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, []byte("héllo"), s.Raw)
+	assert.Equal(t, [6]byte{'h', 0xc3, 0xa9, 'l', 'l', 'o'}, s.RawArray)
+	assert.Equal(t, []byte("hello???"), s.Base64)
+	assert.Equal(t, []byte("hello???"), s.Unpadded)
+	assert.Equal(t, []byte("hello???"), s.Base64URL)
+	assert.Equal(t, []byte{0xde, 0xad, 0xbe, 0xef}, s.Hex)
+	assert.Equal(t, [4]byte{0xde, 0xad, 0xbe, 0xef}, s.HexArray)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, []byte{0xde, 0xad, 0xbe, 0xef}, *s.Pointer)
+	assert.NotNil(t, s.RawJSON)
+	assert.Equal(t, json.RawMessage("héllo"), *s.RawJSON)
+}
+
+func TestFillBytesWithInvalidValue(t *testing.T) {
+
+	var s struct {
+		Base64 []byte  `foo:"bar" encoding:"base64"`
+		Hex    [2]byte `foo:"bar" encoding:"hex"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				assert.Equal(t, "bar", field)
+				return Value("not*encoded"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	for _, e := range errs {
+		assert.Equal(t, "not*encoded", e.Value)
+		assert.True(t, errors.Is(e, ErrConversion))
+	}
+	assert.Nil(t, s.Base64)
+}
+
+func TestFillBytesWithInvalidEncoding(t *testing.T) {
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("abc"), nil
+			},
+		},
+	}
+
+	var unknown struct {
+		Bytes []byte `foo:"bar" encoding:"base32"`
+	}
+	assert.True(t, errors.Is(From(sources).To(&unknown), ErrInvalidTag))
+
+	var noBytes struct {
+		String string `foo:"bar" encoding:"hex"`
+	}
+	assert.True(t, errors.Is(From(sources).To(&noBytes), ErrInvalidTag))
+}
+
+func TestFillIgnoresForeignTags(t *testing.T) {
+
+	var s struct {
+		Foo      string `foo:"foo"`
+		Encoding string `json:"encoding" encoding:"utf8"`
+		Split    int    `json:"split" split:","`
+		Required string `json:"required" required:"yes"`
+		Missing  string `json:"missing" required:"true"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("bar"), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, "bar", s.Foo)
+
+	// the tags are still validated for fields a source fills
+	var tagged struct {
+		Foo      string `foo:"foo"`
+		Encoding string `foo:"encoding" encoding:"utf8"`
+		Split    int    `foo:"split" split:","`
+		Required string `foo:"required" required:"yes"`
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &tagged)
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 3)
+	for _, e := range errs {
+		assert.True(t, errors.Is(e, ErrInvalidTag))
+	}
+	assert.Equal(t, "bar", tagged.Foo)
+}
+
+func TestFillInterface(t *testing.T) {
+
+	var s struct {
+		Single  interface{}            `foo:"single"`
+		Multi   interface{}            `foo:"multi"`
+		Pointer *interface{}           `foo:"single"`
+		Map     map[string]interface{} `foo:"map"`
+		Slice   []interface{}          `foo:"multi"`
+		Split   interface{}            `foo:"split"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "single":
+					return Value("1"), nil
+				case "multi":
+					return Values([]string{"true", "1.5", "abc"}), nil
+				case "map":
+					return Values([]string{"a=1", "b=x"}), nil
+				case "split":
+					return Split("a,b", ","), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, "1", s.Single)
+	assert.Equal(t, []string{"true", "1.5", "abc"}, s.Multi)
+	assert.NotNil(t, s.Pointer)
+	assert.Equal(t, "1", *s.Pointer)
+	assert.Equal(t, map[string]interface{}{"a": "1", "b": "x"}, s.Map)
+	assert.Equal(t, []interface{}{"true", "1.5", "abc"}, s.Slice)
+	assert.Equal(t, []string{"a", "b"}, s.Split)
+}
+
+func TestDecodeInferTypes(t *testing.T) {
+
+	var s struct {
+		Int    interface{} `foo:"int"`
+		Float  interface{} `foo:"float"`
+		Bool   interface{} `foo:"bool"`
+		String interface{} `foo:"string"`
+		Words  interface{} `foo:"words"`
+		Multi  interface{} `foo:"multi"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "int":
+					return Value("-42"), nil
+				case "float":
+					return Value("1.5e3"), nil
+				case "bool":
+					return Value("false"), nil
+				case "string":
+					return Value("abc"), nil
+				case "words":
+					return Values([]string{"inf", "NaN"}), nil
+				}
+				return Values([]string{"1", "true", "x"}), nil
+			},
+		},
+	}
+
+	assert.NoError(t, NewDecoder(InferTypes()).Decode(sources, &s))
+	assert.Equal(t, int64(-42), s.Int)
+	assert.Equal(t, float64(1500), s.Float)
+	assert.Equal(t, false, s.Bool)
+	assert.Equal(t, "abc", s.String)
+	assert.Equal(t, []interface{}{"inf", "NaN"}, s.Words)
+	assert.Equal(t, []interface{}{int64(1), true, "x"}, s.Multi)
+}
+
+func TestFillNonEmptyInterface(t *testing.T) {
+
+	var s struct {
+		Stringer fmt.Stringer `foo:"bar"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value("abc"), nil
+			},
+		},
+	}
+
+	err := From(sources).To(&s)
+	assert.True(t, errors.Is(err, ErrUnsupportedKind))
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package httpsource provides handgover sources for the values of an
+// *http.Request.
+//
+//	type MyRequest struct {
+//		Count   int    `query:"count"`
+//		TraceID string `header:"X-Trace-Id"`
+//	}
+//
+//	var myRequest MyRequest
+//	err := httpsource.Bind(r, &myRequest)
+package httpsource
+
+import (
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/newstore-oss/handgover"
+)
+
+// Tags of the sources in this package.
+const (
+	PathTag      = "path"
+	QueryTag     = "query"
+	HeaderTag    = "header"
+	CookieTag    = "cookie"
+	FormTag      = "form"
+	PostFormTag  = "postform"
+	MultipartTag = "multipart"
+)
+
+// DefaultMaxMemory is the maxMemory used by Bind to parse multipart forms.
+const DefaultMaxMemory = 32 << 20 // 32 MB
+
+// Sources returns all sources of this package in the order path, query,
+// header, cookie, form, postform and multipart.
+func Sources(r *http.Request) []handgover.Source {
+	return []handgover.Source{
+		Path(r),
+		Query(r),
+		Header(r),
+		Cookie(r),
+		Form(r),
+		PostForm(r),
+		MultipartForm(r, DefaultMaxMemory),
+	}
+}
+
+// Bind fills v with the values of r from all sources of this package. It
+// stops if the context of r is done, e.g. because the client went away.
+func Bind(r *http.Request, v interface{}) error {
+	return handgover.From(Sources(r)).ToContext(r.Context(), v)
+}
+
+// PathParams looks up the parameters of the matched route of a request. It
+// adapts the parameters of http.ServeMux or any router to the path source.
+type PathParams interface {
+	// PathParam returns the value of the named parameter or an empty string
+	// if the route has no such parameter.
+	PathParam(r *http.Request, name string) string
+}
+
+// PathParamsFunc is a function implementing PathParams, e.g. to wrap the
+// lookup of a router:
+//
+//	httpsource.PathWith(r, httpsource.PathParamsFunc(func(r *http.Request, name string) string {
+//		return chi.URLParam(r, name)
+//	}))
+type PathParamsFunc func(r *http.Request, name string) string
+
+// PathParam calls f(r, name).
+func (f PathParamsFunc) PathParam(r *http.Request, name string) string {
+	return f(r, name)
+}
+
+// ServeMux provides the wildcards matched by the pattern of http.ServeMux,
+// e.g. {id} of "GET /orders/{id}".
+var ServeMux PathParams = PathParamsFunc((*http.Request).PathValue)
+
+// Path returns a source for the wildcards of the http.ServeMux pattern which
+// matched r.
+func Path(r *http.Request) handgover.Source {
+	return PathWith(r, ServeMux)
+}
+
+// PathWith returns a source for the path parameters of r provided by params.
+func PathWith(r *http.Request, params PathParams) handgover.Source {
+	return handgover.Source{
+		Tag: PathTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			v := params.PathParam(r, field)
+			if v == "" {
+				return nil, nil
+			}
+			return handgover.Value(v), nil
+		},
+	}
+}
+
+// Query returns a source for the URL query values of r. The query is parsed
+// once on first use.
+//
+// Without a value for the field name itself, parameters in the form
+// field[key]=value are provided as key/value pairs to fill map fields.
+func Query(r *http.Request) handgover.Source {
+	var (
+		once  sync.Once
+		query url.Values
+	)
+
+	return handgover.Source{
+		Tag: QueryTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			once.Do(func() {
+				query = r.URL.Query()
+			})
+			return lookup(query, field), nil
+		},
+	}
+}
+
+// Header returns a source for the header values of r. The field name is
+// canonicalized, so `header:"x-trace-id"` matches the header X-Trace-Id.
+//
+// A field name ending with * matches all headers with that prefix, which are
+// provided as key/value pairs to fill map fields. E.g. `header:"X-Label-*"`
+// provides the header X-Label-Env with the key Env.
+func Header(r *http.Request) handgover.Source {
+	return handgover.Source{
+		Tag: HeaderTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			prefix, ok := strings.CutSuffix(field, "*")
+			if !ok {
+				return values(r.Header[textproto.CanonicalMIMEHeaderKey(field)]), nil
+			}
+
+			prefix = textproto.CanonicalMIMEHeaderKey(prefix)
+			kv := map[string][]string{}
+			for name, v := range r.Header {
+				if key, ok := strings.CutPrefix(name, prefix); ok && key != "" {
+					kv[key] = v
+				}
+			}
+			return keyValues(kv), nil
+		},
+	}
+}
+
+// Cookie returns a source for the cookie values of r. A cookie sent multiple
+// times provides multiple values.
+func Cookie(r *http.Request) handgover.Source {
+	return handgover.Source{
+		Tag: CookieTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			var v []string
+			for _, cookie := range r.Cookies() {
+				if cookie.Name == field {
+					v = append(v, cookie.Value)
+				}
+			}
+			return values(v), nil
+		},
+	}
+}
+
+// Form returns a source for the form values of r, which includes the URL query
+// as well as the body of POST, PUT and PATCH requests, either URL-encoded or
+// multipart. See http.Request.FormValue. Map fields are filled like by Query.
+func Form(r *http.Request) handgover.Source {
+	return handgover.Source{
+		Tag: FormTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			if err := parseForm(r); err != nil {
+				return nil, err
+			}
+			return lookup(r.Form, field), nil
+		},
+	}
+}
+
+// PostForm returns a source for the values of the form body of POST, PUT and
+// PATCH requests, either URL-encoded or multipart. See
+// http.Request.PostFormValue. Map fields are filled like by Query.
+func PostForm(r *http.Request) handgover.Source {
+	return handgover.Source{
+		Tag: PostFormTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			if err := parseForm(r); err != nil {
+				return nil, err
+			}
+			return lookup(r.PostForm, field), nil
+		},
+	}
+}
+
+// MultipartForm returns a source for the values of a multipart form body.
+// Requests which aren't multipart don't provide any value. See
+// http.Request.ParseMultipartForm for maxMemory.
+func MultipartForm(r *http.Request, maxMemory int64) handgover.Source {
+	return handgover.Source{
+		Tag: MultipartTag,
+		Get: func(field string) (handgover.Valuer, error) {
+			err := r.ParseMultipartForm(maxMemory)
+			if err == http.ErrNotMultipart {
+				return nil, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			return values(r.MultipartForm.Value[field]), nil
+		},
+	}
+}
+
+// parseForm parses the form values of r including a multipart body with
+// DefaultMaxMemory, like http.Request.FormValue.
+func parseForm(r *http.Request) error {
+	// ParseMultipartForm drops the errors of ParseForm for requests which
+	// aren't multipart
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	err := r.ParseMultipartForm(DefaultMaxMemory)
+	if err == http.ErrNotMultipart {
+		return nil
+	}
+	return err
+}
+
+// lookup returns the values of field or, if there are none, the values of all
+// keys in the form field[key] as key/value pairs.
+func lookup(form url.Values, field string) handgover.Valuer {
+	if v := form[field]; len(v) > 0 {
+		return handgover.Values(v)
+	}
+
+	kv := map[string][]string{}
+	for name, v := range form {
+		key, ok := strings.CutPrefix(name, field+"[")
+		if !ok {
+			continue
+		}
+		if key, ok = strings.CutSuffix(key, "]"); ok {
+			kv[key] = v
+		}
+	}
+	return keyValues(kv)
+}
+
+// keyValues converts kv into a handgover.Valuer, keeping nil for missing
+// values.
+func keyValues(kv map[string][]string) handgover.Valuer {
+	if len(kv) == 0 {
+		return nil
+	}
+	return handgover.KeyValues(kv)
+}
+
+// values converts v into a handgover.Valuer, keeping nil for missing values.
+func values(v []string) handgover.Valuer {
+	if len(v) == 0 {
+		return nil
+	}
+	return handgover.Values(v)
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package httpsource
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/newstore-oss/handgover"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPath(t *testing.T) {
+
+	var s struct {
+		ID     int     `path:"id"`
+		Item   string  `path:"item"`
+		Absent *string `path:"absent"`
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orders/{id}/items/{item}", func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, handgover.From([]handgover.Source{Path(r)}).To(&s))
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/orders/42/items/abc", nil))
+
+	assert.Equal(t, 42, s.ID)
+	assert.Equal(t, "abc", s.Item)
+	assert.Nil(t, s.Absent)
+}
+
+func TestPathWith(t *testing.T) {
+
+	var s struct {
+		ID int `path:"id"`
+	}
+
+	params := PathParamsFunc(func(r *http.Request, name string) string {
+		assert.Equal(t, "id", name)
+		return "42"
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	assert.NoError(t, handgover.From([]handgover.Source{PathWith(r, params)}).To(&s))
+	assert.Equal(t, 42, s.ID)
+}
+
+func TestQuery(t *testing.T) {
+
+	var s struct {
+		Count  int      `query:"count"`
+		Sort   []string `query:"sort"`
+		Absent *string  `query:"absent"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?count=100&sort=name&sort=-created", nil)
+
+	assert.NoError(t, handgover.From([]handgover.Source{Query(r)}).To(&s))
+	assert.Equal(t, 100, s.Count)
+	assert.Equal(t, []string{"name", "-created"}, s.Sort)
+	assert.Nil(t, s.Absent)
+}
+
+func TestQueryMap(t *testing.T) {
+
+	var s struct {
+		Labels map[string]string `query:"labels"`
+		Counts map[string]int    `query:"counts"`
+		Flat   map[string]string `query:"flat"`
+		Absent map[string]string `query:"absent"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?labels[env]=prod&labels[team]=orders&counts[a]=1&flat=a=b&other[x]=y", nil)
+
+	assert.NoError(t, handgover.From([]handgover.Source{Query(r)}).To(&s))
+	assert.Equal(t, map[string]string{"env": "prod", "team": "orders"}, s.Labels)
+	assert.Equal(t, map[string]int{"a": 1}, s.Counts)
+	assert.Equal(t, map[string]string{"a": "b"}, s.Flat)
+	assert.Nil(t, s.Absent)
+}
+
+func TestHeader(t *testing.T) {
+
+	var s struct {
+		TraceID string   `header:"x-trace-id"`
+		Accept  []string `header:"Accept"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Trace-Id", "abc")
+	r.Header.Add("Accept", "text/html")
+	r.Header.Add("Accept", "application/json")
+
+	assert.NoError(t, handgover.From([]handgover.Source{Header(r)}).To(&s))
+	assert.Equal(t, "abc", s.TraceID)
+	assert.Equal(t, []string{"text/html", "application/json"}, s.Accept)
+}
+
+func TestHeaderMap(t *testing.T) {
+
+	var s struct {
+		Labels map[string]string `header:"x-label-*"`
+		Absent map[string]string `header:"X-Absent-*"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("X-Label-Env", "prod")
+	r.Header.Set("X-Label-Team", "orders")
+	r.Header.Set("X-Label-", "empty")
+	r.Header.Set("X-Other", "other")
+
+	assert.NoError(t, handgover.From([]handgover.Source{Header(r)}).To(&s))
+	assert.Equal(t, map[string]string{"Env": "prod", "Team": "orders"}, s.Labels)
+	assert.Nil(t, s.Absent)
+}
+
+func TestCookie(t *testing.T) {
+
+	var s struct {
+		Session string   `cookie:"session"`
+		Flags   []string `cookie:"flag"`
+		Absent  *string  `cookie:"absent"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	r.AddCookie(&http.Cookie{Name: "flag", Value: "a"})
+	r.AddCookie(&http.Cookie{Name: "flag", Value: "b"})
+
+	assert.NoError(t, handgover.From([]handgover.Source{Cookie(r)}).To(&s))
+	assert.Equal(t, "abc", s.Session)
+	assert.Equal(t, []string{"a", "b"}, s.Flags)
+	assert.Nil(t, s.Absent)
+}
+
+func TestFormAndPostForm(t *testing.T) {
+
+	var s struct {
+		Name      string `form:"name"`
+		Count     int    `form:"count"`
+		PostName  string `postform:"name"`
+		PostCount *int   `postform:"count"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/?count=100", strings.NewReader("name=test"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	assert.NoError(t, handgover.From([]handgover.Source{Form(r), PostForm(r)}).To(&s))
+	assert.Equal(t, "test", s.Name)
+	assert.Equal(t, 100, s.Count)
+	assert.Equal(t, "test", s.PostName)
+	assert.Nil(t, s.PostCount)
+}
+
+func TestFormWithInvalidBody(t *testing.T) {
+
+	var s struct {
+		Name string `form:"name"`
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=%zz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	err := handgover.From([]handgover.Source{Form(r)}).To(&s)
+	assert.True(t, errors.Is(err, handgover.ErrSource))
+}
+
+func TestMultipartForm(t *testing.T) {
+
+	var s struct {
+		Name  string   `multipart:"name"`
+		Tags  []string `multipart:"tag"`
+		Count *int     `multipart:"count"`
+	}
+
+	var (
+		body   bytes.Buffer
+		writer = multipart.NewWriter(&body)
+	)
+	assert.NoError(t, writer.WriteField("name", "test"))
+	assert.NoError(t, writer.WriteField("tag", "a"))
+	assert.NoError(t, writer.WriteField("tag", "b"))
+	assert.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	assert.NoError(t, handgover.From([]handgover.Source{MultipartForm(r, DefaultMaxMemory)}).To(&s))
+	assert.Equal(t, "test", s.Name)
+	assert.Equal(t, []string{"a", "b"}, s.Tags)
+	assert.Nil(t, s.Count)
+}
+
+func TestFormWithMultipartBody(t *testing.T) {
+
+	var s struct {
+		A    string `form:"a"`
+		B    string `multipart:"b"`
+		C    string `form:"c"`
+		Post string `postform:"a"`
+		Q    string `form:"q"`
+	}
+
+	var (
+		body   bytes.Buffer
+		writer = multipart.NewWriter(&body)
+	)
+	assert.NoError(t, writer.WriteField("a", "1"))
+	assert.NoError(t, writer.WriteField("b", "2"))
+	assert.NoError(t, writer.WriteField("c", "3"))
+	assert.NoError(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/?q=test", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	// the form sources don't depend on the multipart source running first
+	assert.NoError(t, Bind(r, &s))
+	assert.Equal(t, "1", s.A)
+	assert.Equal(t, "2", s.B)
+	assert.Equal(t, "3", s.C)
+	assert.Equal(t, "1", s.Post)
+	assert.Equal(t, "test", s.Q)
+}
+
+func TestMultipartFormWithoutMultipartRequest(t *testing.T) {
+
+	var s struct {
+		Name string `multipart:"name"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?name=test", nil)
+
+	assert.NoError(t, handgover.From([]handgover.Source{MultipartForm(r, DefaultMaxMemory)}).To(&s))
+	assert.Equal(t, "", s.Name)
+}
+
+func TestBind(t *testing.T) {
+
+	var s struct {
+		ID      string `path:"id"`
+		Count   int    `query:"count"`
+		Offset  int    `query:"offset" header:"X-Offset"`
+		TraceID string `header:"X-Trace-Id"`
+		Session string `cookie:"session"`
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/?count=100&offset=abc", nil)
+	r.Header.Set("X-Offset", "20")
+	r.Header.Set("X-Trace-Id", "abc")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "def"})
+
+	err := Bind(r, &s)
+	assert.Error(t, err)
+
+	hErr, ok := handgover.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, "offset", hErr.Field)
+	assert.Equal(t, QueryTag, hErr.Source)
+	assert.Equal(t, "abc", hErr.Value)
+
+	r = httptest.NewRequest(http.MethodGet, "/?count=100", nil)
+	r.SetPathValue("id", "42")
+	r.Header.Set("X-Offset", "20")
+	r.Header.Set("X-Trace-Id", "abc")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "def"})
+
+	assert.NoError(t, Bind(r, &s))
+	assert.Equal(t, "42", s.ID)
+	assert.Equal(t, 100, s.Count)
+	assert.Equal(t, 20, s.Offset)
+	assert.Equal(t, "abc", s.TraceID)
+	assert.Equal(t, "def", s.Session)
+}
+
+func TestBindCanceledRequest(t *testing.T) {
+
+	var s struct {
+		Count int `query:"count"`
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	r := httptest.NewRequest(http.MethodGet, "/?count=100", nil).WithContext(ctx)
+
+	assert.True(t, errors.Is(Bind(r, &s), context.Canceled))
+	assert.Zero(t, s.Count)
+}
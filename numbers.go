@@ -0,0 +1,119 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	bigIntType   = reflect.TypeOf(big.Int{})
+	bigFloatType = reflect.TypeOf(big.Float{})
+	bigRatType   = reflect.TypeOf(big.Rat{})
+)
+
+// newNumberSetter returns the setter of the complex and math/big number types
+// or nil for any other type.
+func newNumberSetter(t reflect.Type) setter {
+	switch t {
+	case bigIntType:
+		return setBigInt
+	case bigFloatType:
+		return setBigFloat
+	case bigRatType:
+		return setBigRat
+	}
+
+	switch t.Kind() {
+	case reflect.Complex64:
+		return newComplexSetter(64)
+	case reflect.Complex128:
+		return newComplexSetter(128)
+	}
+	return nil
+}
+
+func newComplexSetter(bitSize int) setter {
+	return func(property reflect.Value, values []string) error {
+		c, err := strconv.ParseComplex(values[0], bitSize)
+		if err != nil {
+			return err
+		}
+		property.SetComplex(c)
+		return nil
+	}
+}
+
+// setBigInt parses decimal integers of any size.
+func setBigInt(property reflect.Value, values []string) error {
+	i, ok := new(big.Int).SetString(values[0], 10)
+	if !ok {
+		return &parseError{value: values[0], as: "big.Int", err: strconv.ErrSyntax}
+	}
+	property.Set(reflect.ValueOf(i).Elem())
+	return nil
+}
+
+// setBigFloat parses floats with a precision of at least 64 bits, which is
+// increased to keep all digits of longer values.
+func setBigFloat(property reflect.Value, values []string) error {
+	prec := uint(math.Ceil(float64(significantDigits(values[0])) * math.Log2(10)))
+	if prec < 64 {
+		prec = 64
+	}
+
+	f, _, err := big.ParseFloat(values[0], 10, prec, big.ToNearestEven)
+	if err != nil {
+		return &parseError{value: values[0], as: "big.Float", err: strconv.ErrSyntax}
+	}
+	property.Set(reflect.ValueOf(f).Elem())
+	return nil
+}
+
+// setBigRat parses fractions like 1/3 as well as decimals, which are kept
+// exactly.
+func setBigRat(property reflect.Value, values []string) error {
+	r, ok := new(big.Rat).SetString(values[0])
+	if !ok {
+		return &parseError{value: values[0], as: "big.Rat", err: strconv.ErrSyntax}
+	}
+	property.Set(reflect.ValueOf(r).Elem())
+	return nil
+}
+
+// significantDigits counts the decimal digits of the mantissa of value,
+// ignoring leading zeros.
+func significantDigits(value string) int {
+	if i := strings.IndexAny(value, "eE"); i >= 0 {
+		value = value[:i]
+	}
+
+	digits := 0
+	for _, r := range strings.TrimLeft(value, "+-0.") {
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits
+}
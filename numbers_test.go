@@ -0,0 +1,184 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"errors"
+	"math/big"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillComplex(t *testing.T) {
+
+	var s struct {
+		C64  complex64    `foo:"c64"`
+		C128 complex128   `foo:"c128"`
+		Ptr  *complex128  `foo:"real"`
+		List []complex128 `foo:"list"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "c64":
+					return Value("1.5+2i"), nil
+				case "c128":
+					return Value("(-3-0.25i)"), nil
+				case "real":
+					return Value("7"), nil
+				case "list":
+					return Values([]string{"1i", "2+2i"}), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, complex64(1.5+2i), s.C64)
+	assert.Equal(t, complex128(-3-0.25i), s.C128)
+	assert.NotNil(t, s.Ptr)
+	assert.Equal(t, complex128(7), *s.Ptr)
+	assert.Equal(t, []complex128{1i, 2 + 2i}, s.List)
+}
+
+func TestFillBigNumbers(t *testing.T) {
+
+	var s struct {
+		Int   *big.Int   `foo:"int"`
+		Float *big.Float `foo:"float"`
+		Rat   *big.Rat   `foo:"rat"`
+		Dec   *big.Rat   `foo:"dec"`
+		Value big.Int    `foo:"value"`
+		Ints  []*big.Int `foo:"ints"`
+	}
+
+	const (
+		huge  = "123456789012345678901234567890123456789"
+		float = "3.14159265358979323846264338327950288419716939937510"
+	)
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "int":
+					return Value(huge), nil
+				case "float":
+					return Value(float), nil
+				case "rat":
+					return Value("1/3"), nil
+				case "dec":
+					return Value("0.1"), nil
+				case "value":
+					return Value("-0042"), nil
+				case "ints":
+					return Values([]string{"1", huge}), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, huge, s.Int.String())
+	assert.Equal(t, float, s.Float.Text('f', 50))
+	assert.Equal(t, "1/3", s.Rat.String())
+	assert.Equal(t, "1/10", s.Dec.String())
+	assert.Equal(t, "-42", s.Value.String())
+	assert.Len(t, s.Ints, 2)
+	assert.Equal(t, huge, s.Ints[1].String())
+}
+
+func TestFillBigFloatPrecision(t *testing.T) {
+
+	var s struct {
+		Small *big.Float `foo:"small"`
+		Long  *big.Float `foo:"long"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				if field == "small" {
+					return Value("1.5"), nil
+				}
+				return Value("0.000123456789012345678901234567890e10"), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, uint(64), s.Small.Prec())
+	assert.True(t, s.Long.Prec() > 64)
+	assert.Equal(t, "1234567.89012345678901234567890", s.Long.Text('f', 23))
+}
+
+func TestFillNumberErrors(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		target interface{}
+		value  string
+	}{
+		{"complex", &struct {
+			V complex128 `foo:"v"`
+		}{}, "1+"},
+		{"big.Int", &struct {
+			V *big.Int `foo:"v"`
+		}{}, "1.5"},
+		{"big.Float", &struct {
+			V *big.Float `foo:"v"`
+		}{}, "abc"},
+		{"big.Rat", &struct {
+			V *big.Rat `foo:"v"`
+		}{}, "1/0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sources := []Source{
+				{
+					Tag: "foo",
+					Get: func(field string) (Valuer, error) {
+						return Value(tt.value), nil
+					},
+				},
+			}
+
+			err := From(sources).To(tt.target)
+			assert.True(t, errors.Is(err, ErrConversion))
+
+			hErr, ok := FromError(err)
+			assert.True(t, ok)
+			assert.Equal(t, "v", hErr.Field)
+			assert.Equal(t, tt.value, hErr.Value)
+			assert.True(t, errors.Is(err, strconv.ErrSyntax))
+			assert.NotContains(t, err.Error(), "strconv.big")
+		})
+	}
+}
@@ -0,0 +1,422 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+)
+
+// plan holds everything needed to fill a struct type, which is computed only
+// once per type and shared across all goroutines afterwards.
+type plan struct {
+	fields []fieldPlan
+
+	// bindings caches the binding of the fields per list of source tags.
+	mu       sync.RWMutex
+	bindings map[string]binding
+}
+
+// fieldPlan describes how a single struct field is filled.
+//
+// Struct fields without a tag of the given sources are not decoded as a whole,
+// instead their own fields get filled by the nested plan.
+type fieldPlan struct {
+	index     int
+	name      string
+	tags      structTags
+	set       setter
+	setPairs  pairSetter
+	nested    *plan
+	ptr       bool
+	anonymous bool
+	multi     bool
+	split     string
+	defaults  []string
+	required  bool
+
+	// splitTagged reports whether split is set by the split tag of the field
+	// instead of the decoder.
+	splitTagged bool
+
+	// invalid is the error of an invalid encoding, split or required tag. It
+	// is only reported if a source fills the field, since other packages use
+	// tags of the same names.
+	invalid *Error
+}
+
+// separator returns the separator of the values of v for the field. The split
+// tag of the field replaces the separator of a Split valuer, which replaces
+// the one of the decoder.
+func (field fieldPlan) separator(v Valuer) string {
+	if s, ok := v.(split); ok && field.multi && !field.splitTagged {
+		return s.sep
+	}
+	return field.split
+}
+
+// binding holds the tags of the fields of a plan for the tags of a list of
+// sources, so the struct tags are only looked up once per list.
+type binding []fieldTags
+
+// fieldTags holds the values of the tags of a field per source.
+type fieldTags struct {
+	values []string
+	ok     []bool
+	// tagged reports whether the field carries the tag of at least one
+	// source.
+	tagged bool
+}
+
+// key returns the name the field is looked up with in the i-th source and
+// reports whether the field has the tag of the source.
+func (tags fieldTags) key(i int, prefixes []string) (string, bool) {
+	if !tags.ok[i] {
+		return "", false
+	}
+	if prefixes != nil {
+		return prefixes[i] + tags.values[i], true
+	}
+	return tags.values[i], true
+}
+
+// bind returns the binding of the plan for the sources.
+func (p *plan) bind(sources Sources) binding {
+	// the key is built on the stack for lists of a few short tags, a map
+	// lookup with the converted bytes doesn't allocate either
+	var buf [64]byte
+	key := buf[:0]
+	for _, source := range sources {
+		key = append(key, source.Tag...)
+		key = append(key, 0)
+	}
+
+	p.mu.RLock()
+	b, ok := p.bindings[string(key)]
+	p.mu.RUnlock()
+	if ok {
+		return b
+	}
+
+	b = make(binding, len(p.fields))
+	for i, field := range p.fields {
+		tags := fieldTags{
+			values: make([]string, len(sources)),
+			ok:     make([]bool, len(sources)),
+		}
+		for j, source := range sources {
+			tags.values[j], tags.ok[j] = field.tags.lookup(source.Tag)
+			tags.tagged = tags.tagged || tags.ok[j]
+		}
+		b[i] = tags
+	}
+
+	p.mu.Lock()
+	if p.bindings == nil {
+		p.bindings = map[string]binding{}
+	}
+	p.bindings[string(key)] = b
+	p.mu.Unlock()
+	return b
+}
+
+// defaultTag is the struct tag holding the value of a field which is used if
+// none of the sources provided one.
+const defaultTag = "default"
+
+// splitTag is the struct tag holding the separator which splits the values of
+// a slice or map field into its items.
+const splitTag = "split"
+
+// encodingTag is the struct tag holding the encoding of a []byte or [N]byte
+// field, see byteEncodings.
+const encodingTag = "encoding"
+
+// requiredTag is the struct tag marking a field which must be provided by at
+// least one of the sources.
+const requiredTag = "required"
+
+// compiled is the cache entry of a struct type.
+type compiled struct {
+	plan *plan
+	err  error
+}
+
+// planOf returns the cached plan of the given struct type or compiles it.
+func (d *Decoder) planOf(t reflect.Type) (*plan, error) {
+	c, ok := d.plans.Load(t)
+	if !ok {
+		p, err := d.compilePlan(t)
+		c, _ = d.plans.LoadOrStore(t, compiled{plan: p, err: err})
+	}
+	return c.(compiled).plan, c.(compiled).err
+}
+
+func (d *Decoder) compilePlan(t reflect.Type) (*plan, error) {
+	return d.compileStruct(t, map[reflect.Type]bool{})
+}
+
+// compileStruct compiles the plan of t. Types which are already being compiled
+// further up are kept in visiting and don't get descended into again, to
+// terminate on recursive types.
+func (d *Decoder) compileStruct(t reflect.Type, visiting map[reflect.Type]bool) (*plan, error) {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	p := &plan{}
+	for i := 0; i < t.NumField(); i++ {
+		fp, ok, err := d.compileField(t.Field(i), visiting)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			fp.index = i
+			p.fields = append(p.fields, fp)
+		}
+	}
+	return p, nil
+}
+
+// compileField compiles the plan of a single field. It reports false if the
+// field can't be filled at all.
+func (d *Decoder) compileField(field reflect.StructField, visiting map[reflect.Type]bool) (fieldPlan, bool, error) {
+	var (
+		fp       = fieldPlan{name: field.Name}
+		exported = field.PkgPath == ""
+	)
+
+	structType, ptr := d.nestedStruct(field.Type)
+	// unexported embedded structs are the only unexported fields which can
+	// be descended into, their exported fields are still settable.
+	if structType != nil && !visiting[structType] && (exported || field.Anonymous && !ptr) {
+		nested, err := d.compileStruct(structType, visiting)
+		if err != nil {
+			return fp, false, err
+		}
+		if len(nested.fields) > 0 {
+			fp.nested = nested
+			fp.ptr = ptr
+			fp.anonymous = field.Anonymous
+		}
+	}
+
+	if exported {
+		fp.tags = parseTags(field.Tag)
+	}
+
+	if len(fp.tags) == 0 {
+		return fp, fp.nested != nil, nil
+	}
+
+	if err := d.compileValue(field, &fp); err != nil {
+		if _, ok := fp.tags.lookup(defaultTag); ok {
+			return fp, false, err
+		}
+		invalid := err.(Error)
+		fp.invalid = &invalid
+	}
+	return fp, true, nil
+}
+
+// compileValue compiles the setter of the field and the tags describing its
+// values. All errors are of the type Error.
+func (d *Decoder) compileValue(field reflect.StructField, fp *fieldPlan) error {
+	f := format{
+		layout:   fp.tags.value(layoutTag),
+		encoding: fp.tags.value(encodingTag),
+	}
+	if f.layout == "" {
+		f.layout = fp.tags.value(timeFormatTag)
+	}
+
+	if _, ok := byteEncodings[f.encoding]; !ok || f.encoding != "" && !isBytes(field.Type) {
+		err := fmt.Errorf("encoding %q isn't supported by type %s", f.encoding, field.Type)
+		return newError(ErrInvalidTag, field.Name, encodingTag, []string{f.encoding}, err)
+	}
+
+	if field.Type.Kind() == reflect.Map {
+		m := d.newMapSetter(field.Type, f)
+		fp.set, fp.setPairs = m.set, m.setPairs
+	} else {
+		fp.set = d.newSetter(field.Type, f)
+	}
+	fp.multi = multiValued(field.Type)
+
+	if sep, ok := fp.tags.lookup(splitTag); ok {
+		if !fp.multi {
+			err := fmt.Errorf("type %s can't be split", field.Type)
+			return newError(ErrInvalidTag, field.Name, splitTag, []string{sep}, err)
+		}
+		fp.split = sep
+		fp.splitTagged = true
+	} else if fp.multi {
+		fp.split = d.split
+	}
+
+	if def, ok := fp.tags.lookup(defaultTag); ok {
+		fp.defaults = []string{def}
+
+		if fp.split != "" {
+			var err error
+			if fp.defaults, err = splitValues(fp.defaults, fp.split); err != nil {
+				return newError(ErrInvalidTag, field.Name, defaultTag, []string{def}, err)
+			}
+		}
+
+		// convert the default once to report invalid ones right away
+		if err := fp.set(reflect.New(field.Type).Elem(), fp.defaults); err != nil {
+			return newError(ErrInvalidTag, field.Name, defaultTag, fp.defaults, err)
+		}
+	}
+
+	if required, ok := fp.tags.lookup(requiredTag); ok {
+		var err error
+		if fp.required, err = strconv.ParseBool(required); err != nil {
+			return newError(ErrInvalidTag, field.Name, requiredTag, []string{required}, err)
+		}
+	}
+	return nil
+}
+
+// multiValued reports whether each value is an element of t, in contrast to
+// types which are converted from a single value.
+func multiValued(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if !implements(t, unmarshalerType) && implements(t, textUnmarshalerType) {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	case reflect.Map:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	}
+	return false
+}
+
+// isBytes reports whether t is a byte slice or array, or a pointer to one.
+func isBytes(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// nestedStruct returns the struct type of t if the fields of t could be
+// filled individually. ptr is true when t is a pointer to that struct.
+func (d *Decoder) nestedStruct(t reflect.Type) (structType reflect.Type, ptr bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		ptr = true
+	}
+
+	// types with a conversion are filled as a whole
+	if _, ok := d.converters[t]; ok || t.Kind() != reflect.Struct || t == timeType ||
+		implements(t, unmarshalerType) || implements(t, textUnmarshalerType) {
+		return nil, false
+	}
+	return t, ptr
+}
+
+type structTag struct {
+	key   string
+	value string
+}
+
+// structTags are the parsed key/value pairs of a reflect.StructTag.
+type structTags []structTag
+
+// lookup behaves like reflect.StructTag.Lookup.
+func (tags structTags) lookup(key string) (string, bool) {
+	for _, tag := range tags {
+		if tag.key == key {
+			return tag.value, true
+		}
+	}
+	return "", false
+}
+
+// value returns the value of key or an empty string.
+func (tags structTags) value(key string) string {
+	v, _ := tags.lookup(key)
+	return v
+}
+
+// parseTags splits a struct tag into its key/value pairs. It follows the same
+// conventions as reflect.StructTag.Lookup and stops at the first malformed pair.
+func parseTags(tag reflect.StructTag) structTags {
+	var tags structTags
+	for tag != "" {
+		// skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// scan to colon, a space, a quote or a control character
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := string(tag[:i])
+		tag = tag[i+1:]
+
+		// scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		quoted := string(tag[:i+1])
+		tag = tag[i+1:]
+
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			break
+		}
+		tags = append(tags, structTag{key: key, value: value})
+	}
+	return tags
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type benchRequest struct {
+	Count    int           `query:"count"`
+	Offset   int           `query:"offset"`
+	Query    string        `query:"q" header:"X-Query"`
+	Sort     []string      `query:"sort"`
+	Verbose  *bool         `query:"verbose"`
+	Since    time.Time     `query:"since"`
+	Timeout  time.Duration `header:"X-Timeout"`
+	Price    float64       `query:"price"`
+	internal string
+}
+
+var benchSources = Sources{
+	{
+		Tag: "query",
+		Get: func(field string) (Valuer, error) {
+			switch field {
+			case "count":
+				return Value("100"), nil
+			case "offset":
+				return Value("20"), nil
+			case "sort":
+				return Values([]string{"name", "-created"}), nil
+			case "verbose":
+				return Value("true"), nil
+			case "since":
+				return Value("2020-01-02T15:04:05Z"), nil
+			case "price":
+				return Value("9.99"), nil
+			}
+			return nil, nil
+		},
+	},
+	{
+		Tag: "header",
+		Get: func(field string) (Valuer, error) {
+			switch field {
+			case "X-Query":
+				return Value("test"), nil
+			case "X-Timeout":
+				return Value("5s"), nil
+			}
+			return nil, nil
+		},
+	},
+}
+
+func TestParseTags(t *testing.T) {
+
+	tags := []reflect.StructTag{
+		``,
+		`foo:"bar"`,
+		`foo:"bar" john:"doe"`,
+		`foo:"bar"  john:"d\"oe" foo:"baz"`,
+		`foo:"bar" invalid john:"doe"`,
+		`foo:"bar`,
+	}
+
+	for _, tag := range tags {
+		parsed := parseTags(tag)
+		for _, key := range []string{"foo", "john", "invalid", "unknown"} {
+			expectedValue, expectedOk := tag.Lookup(key)
+			value, ok := parsed.lookup(key)
+
+			assert.Equal(t, expectedOk, ok, "tag %q key %q", tag, key)
+			assert.Equal(t, expectedValue, value, "tag %q key %q", tag, key)
+		}
+	}
+}
+
+func TestPlanOfSkipsUntaggedAndUnexportedFields(t *testing.T) {
+
+	p, err := defaultDecoder.planOf(reflect.TypeOf(benchRequest{}))
+	assert.NoError(t, err)
+
+	var indices []int
+	for _, field := range p.fields {
+		indices = append(indices, field.index)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7}, indices)
+}
+
+func TestPlanOfIsSharedAcrossGoroutines(t *testing.T) {
+
+	type s struct {
+		String string `foo:"bar"`
+	}
+
+	var (
+		wg    sync.WaitGroup
+		found = make([]*plan, 16)
+	)
+	for i := range found {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			found[i], _ = defaultDecoder.planOf(reflect.TypeOf(s{}))
+		}(i)
+	}
+	wg.Wait()
+
+	for _, p := range found {
+		assert.True(t, p == found[0])
+	}
+}
+
+func TestPlanBindIsCachedPerSourceTags(t *testing.T) {
+
+	p, err := defaultDecoder.planOf(reflect.TypeOf(benchRequest{}))
+	assert.NoError(t, err)
+
+	b := p.bind(Sources{{Tag: "query"}, {Tag: "header"}})
+	assert.Len(t, b, len(p.fields))
+
+	key, ok := b[2].key(1, nil)
+	assert.True(t, ok)
+	assert.Equal(t, "X-Query", key)
+	key, ok = b[2].key(0, []string{"a.", "b."})
+	assert.True(t, ok)
+	assert.Equal(t, "a.q", key)
+	_, ok = b[6].key(0, nil)
+	assert.False(t, ok)
+	assert.True(t, b[6].tagged)
+
+	// the same tags share the binding, other tags get their own
+	assert.True(t, &b[0] == &p.bind(Sources{{Tag: "query"}, {Tag: "header"}})[0])
+	assert.False(t, p.bind(Sources{{Tag: "header"}})[0].tagged)
+}
+
+func TestBaselineToMatchesTo(t *testing.T) {
+
+	var baseline, s benchRequest
+	assert.NoError(t, baselineTo(benchSources, &baseline))
+	assert.NoError(t, benchSources.To(&s))
+	assert.Equal(t, baseline, s)
+}
+
+func TestFillConcurrently(t *testing.T) {
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var s benchRequest
+			assert.NoError(t, benchSources.To(&s))
+			assert.Equal(t, 100, s.Count)
+			assert.Equal(t, "test", s.Query)
+			assert.Equal(t, []string{"name", "-created"}, s.Sort)
+			assert.Equal(t, 5*time.Second, s.Timeout)
+		}()
+	}
+	wg.Wait()
+}
+
+func BenchmarkTo(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s benchRequest
+		if err := benchSources.To(&s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkToBaseline measures the reflect path To used before plans were
+// cached, which is the reference of BenchmarkTo.
+func BenchmarkToBaseline(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var s benchRequest
+		if err := baselineTo(benchSources, &s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkToWithoutPlanCache drops the cached plan before every call, so it
+// measures To including the compilation of the plan.
+func BenchmarkToWithoutPlanCache(b *testing.B) {
+	b.ReportAllocs()
+	t := reflect.TypeOf(benchRequest{})
+	for i := 0; i < b.N; i++ {
+		var s benchRequest
+		defaultDecoder.plans.Delete(t)
+		if err := benchSources.To(&s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
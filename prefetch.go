@@ -0,0 +1,193 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"context"
+	"sync"
+)
+
+// fetched is the result of a prefetched Get.
+type fetched struct {
+	v   Valuer
+	err error
+}
+
+// fetch gets the values of a source, either a single field with Get or all
+// fields of the source with GetMany or GetManyContext.
+type fetch struct {
+	source int
+	fields []string
+	values map[string]Valuer
+	err    error
+	done   bool
+}
+
+// batched reports whether one of the sources gets the values of all fields at
+// once.
+func (sources Sources) batched() bool {
+	for _, source := range sources {
+		if source.batch() {
+			return true
+		}
+	}
+	return false
+}
+
+// prefetch gets the values of the fields of the plan and stores them in
+// dec.fetched. Batch sources are called once with all their fields, the
+// others per field only if the decoder prefetches. The fetches run with a pool
+// of workers if the decoder prefetches, otherwise one after another. Fields
+// which weren't fetched because the context is done are left out and got by
+// fill on demand.
+func (dec *decoding) prefetch(p *plan, prefixes []string) {
+	var (
+		fetches []fetch
+		batches = make([]int, len(dec.sources))
+		seen    = make([]map[string]bool, len(dec.sources))
+	)
+	for i := range dec.sources {
+		batches[i] = -1
+		seen[i] = map[string]bool{}
+	}
+
+	dec.fields(p, prefixes, func(i int, field string) {
+		if seen[i][field] {
+			return
+		}
+		seen[i][field] = true
+
+		switch {
+		case !dec.sources[i].batch() && dec.workers > 0:
+			fetches = append(fetches, fetch{source: i, fields: []string{field}})
+		case !dec.sources[i].batch():
+		case batches[i] < 0:
+			batches[i] = len(fetches)
+			fetches = append(fetches, fetch{source: i, fields: []string{field}})
+		default:
+			fetches[batches[i]].fields = append(fetches[batches[i]].fields, field)
+		}
+	})
+
+	if dec.workers > 0 {
+		dec.fetchConcurrently(fetches)
+	} else {
+		for j := range fetches {
+			if dec.ctx.Err() != nil {
+				break
+			}
+			fetches[j].get(dec.ctx, dec.sources[fetches[j].source])
+		}
+	}
+
+	dec.fetched = make([]map[string]fetched, len(dec.sources))
+	for i := range dec.sources {
+		dec.fetched[i] = make(map[string]fetched, len(seen[i]))
+	}
+	for _, f := range fetches {
+		if !f.done {
+			continue
+		}
+		for _, field := range f.fields {
+			dec.fetched[f.source][field] = fetched{v: f.values[field], err: f.err}
+		}
+	}
+}
+
+// fetchConcurrently runs the fetches with up to dec.workers workers. The
+// workers only share the context and the sources, so dec stays on the stack of
+// DecodeContext.
+func (dec *decoding) fetchConcurrently(fetches []fetch) {
+	var (
+		next    = make(chan int)
+		wg      sync.WaitGroup
+		ctx     = dec.ctx
+		sources = dec.sources
+	)
+
+	workers := dec.workers
+	if workers > len(fetches) {
+		workers = len(fetches)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range next {
+				if ctx.Err() == nil {
+					fetches[j].get(ctx, sources[fetches[j].source])
+				}
+			}
+		}()
+	}
+
+feed:
+	for j := range fetches {
+		if ctx.Err() != nil {
+			break
+		}
+
+		select {
+		case next <- j:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(next)
+	wg.Wait()
+}
+
+// get gets the values of f from its source.
+func (f *fetch) get(ctx context.Context, source Source) {
+	if source.batch() {
+		f.values, f.err = source.getMany(ctx, f.fields)
+	} else {
+		var v Valuer
+		v, f.err = source.get(ctx, f.fields[0])
+		f.values = map[string]Valuer{f.fields[0]: v}
+	}
+	f.done = true
+}
+
+// fields calls fn with the name of every field of the plan per source in the
+// order fill looks them up.
+func (dec *decoding) fields(p *plan, prefixes []string, fn func(i int, field string)) {
+	b := p.bind(dec.sources)
+	for i, field := range p.fields {
+		if field.nested != nil && !b[i].tagged {
+			nested := prefixes
+			if !field.anonymous {
+				nested = dec.nest(field, prefixes)
+			}
+			dec.fields(field.nested, nested, fn)
+			continue
+		}
+
+		if field.invalid != nil {
+			continue
+		}
+
+		for j := range dec.sources {
+			if name, ok := b[i].key(j, prefixes); ok {
+				fn(j, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type prefetchAddress struct {
+	Street string `foo:"street" bar:"street"`
+	Zip    int    `foo:"zip"`
+}
+
+type prefetchTarget struct {
+	ID       int               `foo:"id" bar:"id"`
+	Names    []string          `foo:"names"`
+	Count    int               `foo:"count" bar:"count"`
+	Labels   map[string]string `foo:"labels"`
+	Missing  string            `foo:"missing" bar:"missing"`
+	Default  int               `foo:"unset" default:"7"`
+	Invalid  int               `bar:"invalid"`
+	Failing  string            `foo:"failing"`
+	Address  prefetchAddress
+	Shipping *prefetchAddress
+	Twice    int `foo:"id"`
+}
+
+// slowSources returns two sources which answer after a delay and count their
+// concurrent calls in inFlight and maxInFlight.
+func slowSources(delay time.Duration, inFlight, maxInFlight *int32) []Source {
+	get := func(values map[string]Valuer) func(string) (Valuer, error) {
+		return func(field string) (Valuer, error) {
+			n := atomic.AddInt32(inFlight, 1)
+			defer atomic.AddInt32(inFlight, -1)
+			for {
+				max := atomic.LoadInt32(maxInFlight)
+				if n <= max || atomic.CompareAndSwapInt32(maxInFlight, max, n) {
+					break
+				}
+			}
+
+			time.Sleep(delay)
+			if field == "failing" {
+				return nil, errors.New("unavailable")
+			}
+			return values[field], nil
+		}
+	}
+
+	nest := func(prefix, name string) string {
+		return prefix + strings.ToLower(name) + "."
+	}
+
+	return []Source{
+		{
+			Tag: "foo",
+			Get: get(map[string]Valuer{
+				"id":              Value("1"),
+				"names":           Values([]string{"a", "b"}),
+				"labels":          KeyValues(map[string][]string{"env": {"prod"}}),
+				"address.street":  Value("Main St"),
+				"address.zip":     Value("12345"),
+				"shipping.zip":    Value("abc"),
+				"shipping.street": Value("Side St"),
+			}),
+			Nest: nest,
+		},
+		{
+			Tag: "bar",
+			Get: get(map[string]Valuer{
+				"id":             Value("2"),
+				"count":          Value("3"),
+				"invalid":        Value("x"),
+				"address.street": Value("Other St"),
+			}),
+			Nest: nest,
+		},
+	}
+}
+
+func TestPrefetchEqualsSerial(t *testing.T) {
+
+	for _, collect := range []bool{false, true} {
+		t.Run(fmt.Sprintf("collect errors %v", collect), func(t *testing.T) {
+			var (
+				serial, prefetched prefetchTarget
+				inFlight, max      int32
+				opts               []Option
+			)
+			if collect {
+				opts = append(opts, CollectErrors())
+			}
+
+			sources := slowSources(0, &inFlight, &max)
+			serialErr := NewDecoder(opts...).Decode(sources, &serial)
+			prefetchErr := NewDecoder(append(opts, Prefetch(4))...).Decode(sources, &prefetched)
+
+			assert.Error(t, serialErr)
+			assert.Equal(t, serialErr, prefetchErr)
+			assert.Equal(t, serial, prefetched)
+		})
+	}
+}
+
+func TestPrefetchConcurrently(t *testing.T) {
+
+	var (
+		s             prefetchTarget
+		inFlight, max int32
+		decoder       = NewDecoder(CollectErrors(), Prefetch(3))
+		sources       = slowSources(5*time.Millisecond, &inFlight, &max)
+	)
+
+	err := decoder.Decode(sources, &s)
+	assert.True(t, errors.Is(err, ErrConversion))
+	assert.True(t, errors.Is(err, ErrSource))
+	assert.Equal(t, int32(3), max)
+
+	assert.Equal(t, 2, s.ID)
+	assert.Equal(t, []string{"a", "b"}, s.Names)
+	assert.Equal(t, 3, s.Count)
+	assert.Equal(t, map[string]string{"env": "prod"}, s.Labels)
+	assert.Equal(t, 7, s.Default)
+	assert.Equal(t, prefetchAddress{Street: "Other St", Zip: 12345}, s.Address)
+	assert.NotNil(t, s.Shipping)
+	assert.Equal(t, "Side St", s.Shipping.Street)
+	assert.Equal(t, 1, s.Twice)
+}
+
+func TestPrefetchConcurrentDecodes(t *testing.T) {
+
+	var (
+		inFlight, max int32
+		decoder       = NewDecoder(CollectErrors(), Prefetch(8))
+		sources       = slowSources(time.Millisecond, &inFlight, &max)
+		expected      prefetchTarget
+	)
+
+	expectedErr := NewDecoder(CollectErrors()).Decode(sources, &expected)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var s prefetchTarget
+			err := decoder.Decode(sources, &s)
+			assert.Equal(t, expectedErr, err)
+			assert.Equal(t, expected, s)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPrefetchContext(t *testing.T) {
+
+	var (
+		s     prefetchTarget
+		calls int32
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetContext: func(ctx context.Context, field string) (Valuer, error) {
+				atomic.AddInt32(&calls, 1)
+				cancel()
+				return Value("1"), nil
+			},
+		},
+	}
+
+	err := NewDecoder(Prefetch(1)).DecodeContext(ctx, sources, &s)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestGetMany(t *testing.T) {
+
+	var (
+		s     prefetchTarget
+		calls [][]string
+	)
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetMany: func(fields []string) (map[string]Valuer, error) {
+				calls = append(calls, fields)
+				return map[string]Valuer{
+					"id":             Value("1"),
+					"names":          Values([]string{"a", "b"}),
+					"address.street": Value("Main St"),
+					"shipping.zip":   Value("12345"),
+				}, nil
+			},
+			Nest: func(prefix, name string) string {
+				return prefix + strings.ToLower(name) + "."
+			},
+		},
+		{
+			Tag: "bar",
+			Get: func(field string) (Valuer, error) {
+				if field == "count" {
+					return Value("3"), nil
+				}
+				return nil, nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+	assert.Equal(t, [][]string{{
+		"id", "names", "count", "labels", "missing", "unset", "failing",
+		"address.street", "address.zip", "shipping.street", "shipping.zip",
+	}}, calls)
+
+	assert.Equal(t, 1, s.ID)
+	assert.Equal(t, []string{"a", "b"}, s.Names)
+	assert.Equal(t, 3, s.Count)
+	assert.Equal(t, 7, s.Default)
+	assert.Equal(t, "Main St", s.Address.Street)
+	assert.NotNil(t, s.Shipping)
+	assert.Equal(t, 12345, s.Shipping.Zip)
+	assert.Equal(t, 1, s.Twice)
+}
+
+func TestGetManyError(t *testing.T) {
+
+	var s struct {
+		Foo string `foo:"foo"`
+		Bar string `foo:"bar"`
+	}
+
+	sourceErr := errors.New("unavailable")
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetMany: func(fields []string) (map[string]Valuer, error) {
+				return nil, sourceErr
+			},
+		},
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(sources, &s)
+
+	var errs Errors
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "foo", errs[0].Field)
+	assert.Equal(t, "bar", errs[1].Field)
+	assert.True(t, errors.Is(err, ErrSource))
+	assert.True(t, errors.Is(err, sourceErr))
+}
+
+func TestGetManyWithPrefetch(t *testing.T) {
+
+	var (
+		inFlight, max int32
+		sources       = slowSources(time.Millisecond, &inFlight, &max)
+		batches       int32
+	)
+
+	// the first source answers all fields at once
+	get := sources[0].Get
+	sources[0].Get = nil
+	sources[0].GetMany = func(fields []string) (map[string]Valuer, error) {
+		atomic.AddInt32(&batches, 1)
+
+		values := map[string]Valuer{}
+		for _, field := range fields {
+			if v, err := get(field); err == nil {
+				values[field] = v
+			}
+		}
+		return values, nil
+	}
+
+	var serial, prefetched prefetchTarget
+	serialErr := NewDecoder(CollectErrors()).Decode(sources, &serial)
+	prefetchErr := NewDecoder(CollectErrors(), Prefetch(4)).Decode(sources, &prefetched)
+
+	assert.True(t, errors.Is(serialErr, ErrConversion))
+	assert.Equal(t, serialErr, prefetchErr)
+	assert.Equal(t, serial, prefetched)
+	assert.Equal(t, "Side St", prefetched.Shipping.Street)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&batches))
+}
+
+func TestGetManyContext(t *testing.T) {
+
+	var s struct {
+		Foo string `foo:"foo"`
+		Bar string `foo:"bar"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetManyContext: func(ctx context.Context, fields []string) (map[string]Valuer, error) {
+				values := map[string]Valuer{}
+				for _, field := range fields {
+					values[field] = Value(ctx.Value(contextKey{}).(string) + field)
+				}
+				return values, nil
+			},
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "ctx-")
+	assert.NoError(t, From(sources).ToContext(ctx, &s))
+	assert.Equal(t, "ctx-foo", s.Foo)
+	assert.Equal(t, "ctx-bar", s.Bar)
+}
+
+func TestGetManyContextCanceled(t *testing.T) {
+
+	var s struct {
+		Foo string `foo:"foo"`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			GetManyContext: func(ctx context.Context, fields []string) (map[string]Valuer, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+		},
+	}
+
+	for _, decoder := range []*Decoder{NewDecoder(), NewDecoder(Prefetch(2))} {
+		err := decoder.DecodeContext(ctx, sources, &s)
+		assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	}
+}
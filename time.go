@@ -0,0 +1,129 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// layoutTag is the struct tag holding the layout of time.Time fields.
+const layoutTag = "layout"
+
+// timeFormatTag is an alternative to the layout tag.
+const timeFormatTag = "time_format"
+
+// epochs maps the named layouts of numeric epoch values to their unit. The
+// values are the number of units since January 1, 1970 UTC and may have a
+// fractional part, e.g. 1714559400.5 for unix.
+var epochs = map[string]time.Duration{
+	"unix":      time.Second,
+	"unixmilli": time.Millisecond,
+	"unixmicro": time.Microsecond,
+	"unixnano":  time.Nanosecond,
+}
+
+// layouts maps names, which can be used in the layout tag or TimeLayout, to
+// layouts of the time package.
+var layouts = map[string]string{
+	"rfc3339":     time.RFC3339,
+	"rfc3339nano": time.RFC3339Nano,
+	"rfc1123":     time.RFC1123,
+	"rfc1123z":    time.RFC1123Z,
+	"rfc822":      time.RFC822,
+	"rfc822z":     time.RFC822Z,
+	"rfc850":      time.RFC850,
+	"ansic":       time.ANSIC,
+	"kitchen":     time.Kitchen,
+	"date":        time.DateOnly,
+	"datetime":    time.DateTime,
+	"time":        time.TimeOnly,
+}
+
+// newTimeSetter parses time.Time values with the given layout. Without one the
+// layout of the decoder is used.
+func (d *Decoder) newTimeSetter(layout string) setter {
+	if layout == "" {
+		layout = d.timeLayout
+	}
+	if named, ok := layouts[layout]; ok {
+		layout = named
+	}
+
+	location := d.timeLocation
+
+	if unit, ok := epochs[layout]; ok {
+		return func(property reflect.Value, values []string) error {
+			t, err := parseEpoch(values[0], unit)
+			if err != nil {
+				return err
+			}
+			property.Set(reflect.ValueOf(t.In(location)))
+			return nil
+		}
+	}
+
+	return func(property reflect.Value, values []string) error {
+		t, err := time.ParseInLocation(layout, values[0], location)
+		if err != nil {
+			return err
+		}
+		property.Set(reflect.ValueOf(t))
+		return nil
+	}
+}
+
+// parseEpoch parses the number of units since January 1, 1970 UTC. The
+// fractional part of the number is kept up to nanoseconds.
+func parseEpoch(value string, unit time.Duration) (time.Time, error) {
+	epochError := func(err error) error {
+		return &parseError{value: value, as: "epoch", err: err}
+	}
+
+	whole, frac, hasFrac := strings.Cut(value, ".")
+
+	n, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, epochError(err.(*strconv.NumError).Err)
+	}
+
+	var nsec int64
+	if hasFrac {
+		if frac == "" || strings.TrimLeft(frac, "0123456789") != "" {
+			return time.Time{}, epochError(strconv.ErrSyntax)
+		}
+
+		// nanoseconds are the smallest unit, so more than 9 digits are cut
+		if len(frac) > 9 {
+			frac = frac[:9]
+		}
+		f, _ := strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+		nsec = f * int64(unit) / int64(time.Second)
+
+		if strings.HasPrefix(whole, "-") {
+			nsec = -nsec
+		}
+	}
+
+	perSecond := int64(time.Second / unit)
+	return time.Unix(n/perSecond, n%perSecond*int64(unit)+nsec), nil
+}
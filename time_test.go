@@ -0,0 +1,206 @@
+// Copyright (c) 2020 NewStore GmbH <tpauling@newstore.com>
+
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package handgover
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func timeSources(t *testing.T) []Source {
+	return []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				switch field {
+				case "rfc3339":
+					return Value("2024-05-01T10:30:00+02:00"), nil
+				case "date":
+					return Value("2024-05-01"), nil
+				case "rfc1123":
+					return Value("Wed, 01 May 2024 10:30:00 GMT"), nil
+				case "unix":
+					return Value("1714559400"), nil
+				case "unixmilli":
+					return Value("1714559400123"), nil
+				case "dates":
+					return Values([]string{"2024-05-01", "2024-05-02"}), nil
+				}
+				t.Errorf("unexpected field %q", field)
+				return nil, nil
+			},
+		},
+	}
+}
+
+func TestFillTime(t *testing.T) {
+
+	var s struct {
+		Default   time.Time   `foo:"rfc3339"`
+		Layout    time.Time   `foo:"date" layout:"2006-01-02"`
+		Date      *time.Time  `foo:"date" layout:"date"`
+		RFC1123   time.Time   `foo:"rfc1123" layout:"rfc1123"`
+		Unix      time.Time   `foo:"unix" layout:"unix"`
+		UnixMilli time.Time   `foo:"unixmilli" layout:"unixmilli"`
+		Dates     []time.Time `foo:"dates" layout:"date"`
+	}
+
+	assert.NoError(t, From(timeSources(t)).To(&s))
+
+	assert.True(t, time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC).Equal(s.Default))
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), s.Layout)
+	assert.NotNil(t, s.Date)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), *s.Date)
+	assert.True(t, time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC).Equal(s.RFC1123))
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), s.Unix)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 123000000, time.UTC), s.UnixMilli)
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC),
+	}, s.Dates)
+}
+
+func TestDecodeTimeLayoutAndLocation(t *testing.T) {
+
+	var s struct {
+		Date    time.Time `foo:"date"`
+		Unix    time.Time `foo:"unix" layout:"unix"`
+		RFC3339 time.Time `foo:"rfc3339" layout:"rfc3339"`
+	}
+
+	location := time.FixedZone("test", 3600)
+	decoder := NewDecoder(TimeLayout("date"), TimeLocation(location))
+
+	assert.NoError(t, decoder.Decode(timeSources(t), &s))
+
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, location), s.Date)
+	assert.Equal(t, location, s.Unix.Location())
+	assert.True(t, time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC).Equal(s.Unix))
+	assert.True(t, time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC).Equal(s.RFC3339))
+
+	// a nil location is UTC
+	decoder = NewDecoder(TimeLayout("date"), TimeLocation(nil))
+
+	assert.NoError(t, decoder.Decode(timeSources(t), &s))
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), s.Date)
+	assert.Equal(t, time.UTC, s.Unix.Location())
+}
+
+func TestFillTimeWithInvalidValue(t *testing.T) {
+
+	var s struct {
+		Date time.Time `foo:"rfc3339" layout:"date"`
+		Unix time.Time `foo:"date" layout:"unix"`
+	}
+
+	err := NewDecoder(CollectErrors()).Decode(timeSources(t), &s)
+
+	var errs Errors
+
+	assert.True(t, errors.As(err, &errs))
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "2024-05-01T10:30:00+02:00", errs[0].Value)
+	assert.Equal(t, "2024-05-01", errs[1].Value)
+	assert.True(t, s.Date.IsZero())
+	assert.True(t, s.Unix.IsZero())
+}
+
+func TestFillTimeEpoch(t *testing.T) {
+
+	var s struct {
+		Unix         time.Time  `foo:"unix" time_format:"unix"`
+		Fractional   time.Time  `foo:"fractional" time_format:"unix"`
+		Negative     time.Time  `foo:"negative" time_format:"unix"`
+		UnixMilli    time.Time  `foo:"unixmilli" time_format:"unixmilli"`
+		FracMilli    *time.Time `foo:"fracmilli" time_format:"unixmilli"`
+		NegMilli     time.Time  `foo:"negmilli" time_format:"unixmilli"`
+		UnixMicro    time.Time  `foo:"unixmicro" time_format:"unixmicro"`
+		UnixNano     time.Time  `foo:"unixnano" time_format:"unixnano"`
+		LongFraction time.Time  `foo:"longfraction" time_format:"unix"`
+		Layout       time.Time  `foo:"unix" layout:"unix" time_format:"date"`
+	}
+
+	sources := []Source{
+		{
+			Tag: "foo",
+			Get: func(field string) (Valuer, error) {
+				return Value(map[string]string{
+					"unix":         "1714559400",
+					"fractional":   "1714559400.25",
+					"negative":     "-1.5",
+					"unixmilli":    "1714559400123",
+					"fracmilli":    "1714559400123.5",
+					"negmilli":     "-1500",
+					"unixmicro":    "1714559400123456",
+					"unixnano":     "1714559400123456789",
+					"longfraction": "1714559400.1234567899",
+				}[field]), nil
+			},
+		},
+	}
+
+	assert.NoError(t, From(sources).To(&s))
+
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC), s.Unix)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 250000000, time.UTC), s.Fractional)
+	assert.Equal(t, time.Unix(-2, 500000000).UTC(), s.Negative)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 123000000, time.UTC), s.UnixMilli)
+	assert.NotNil(t, s.FracMilli)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 123500000, time.UTC), *s.FracMilli)
+	assert.Equal(t, time.Unix(-2, 500000000).UTC(), s.NegMilli)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 123456000, time.UTC), s.UnixMicro)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 123456789, time.UTC), s.UnixNano)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 30, 0, 123456789, time.UTC), s.LongFraction)
+	assert.Equal(t, s.Unix, s.Layout)
+}
+
+func TestFillTimeEpochWithInvalidValue(t *testing.T) {
+
+	values := []string{"abc", "1.", ".5", "1.5e3", "1.-5", "99999999999999999999"}
+
+	for _, value := range values {
+		var s struct {
+			Unix time.Time `foo:"bar" time_format:"unix"`
+		}
+
+		sources := []Source{
+			{
+				Tag: "foo",
+				Get: func(field string) (Valuer, error) {
+					return Value(value), nil
+				},
+			},
+		}
+
+		err := From(sources).To(&s)
+		assert.Error(t, err, value)
+
+		hErr, ok := FromError(err)
+		assert.True(t, ok, value)
+		assert.Equal(t, value, hErr.Value)
+		assert.True(t, strings.HasPrefix(hErr.InnerError.Error(), fmt.Sprintf("parsing %q as epoch: ", value)), value)
+		assert.True(t, s.Unix.IsZero(), value)
+	}
+}